@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// eventPullRequest reads the pull request number from the GitHub Actions event payload file
+// (GITHUB_EVENT_PATH). It supports pull request events and comment events on pull requests.
+func eventPullRequest() (int, error) {
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return 0, errors.New("GITHUB_EVENT_PATH is not set")
+	}
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("reading event: %v", err)
+	}
+
+	var event struct {
+		PullRequest *struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+		Issue *struct {
+			Number      int         `json:"number"`
+			PullRequest interface{} `json:"pull_request"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(bs, &event); err != nil {
+		return 0, fmt.Errorf("parsing event '%s': %v", path, err)
+	}
+
+	switch {
+	case event.PullRequest != nil && event.PullRequest.Number > 0:
+		return event.PullRequest.Number, nil
+	case event.Issue != nil && event.Issue.PullRequest != nil && event.Issue.Number > 0:
+		return event.Issue.Number, nil
+	}
+	return 0, fmt.Errorf("event '%s' is not a pull request event", path)
+}
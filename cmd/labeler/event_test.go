@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventPullRequest(t *testing.T) {
+	tests := map[string]struct {
+		event   string
+		want    int
+		wantErr bool
+	}{
+		"pull request event":          {event: `{"action": "opened", "pull_request": {"number": 42}}`, want: 42},
+		"pull request comment event":  {event: `{"issue": {"number": 7, "pull_request": {"url": "https://api.github.com"}}}`, want: 7},
+		"issue comment event":         {event: `{"issue": {"number": 7}}`, wantErr: true},
+		"push event":                  {event: `{"ref": "refs/heads/master"}`, wantErr: true},
+		"pull request without number": {event: `{"pull_request": {}}`, wantErr: true},
+		"invalid json":                {event: `{"pull_request": `, wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "event.json")
+			require.NoError(t, os.WriteFile(path, []byte(test.event), 0644))
+			t.Setenv("GITHUB_EVENT_PATH", path)
+
+			number, err := eventPullRequest()
+
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.want, number)
+			}
+		})
+	}
+}
+
+func TestEventPullRequest_NoEventFile(t *testing.T) {
+	t.Setenv("GITHUB_EVENT_PATH", "")
+	_, err := eventPullRequest()
+	assert.Error(t, err)
+
+	t.Setenv("GITHUB_EVENT_PATH", filepath.Join(t.TempDir(), "missing.json"))
+	_, err = eventPullRequest()
+	assert.Error(t, err)
+}
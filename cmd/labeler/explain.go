@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ilyam8/periodic-pr-labeler/pkg/mappings"
+	"github.com/ilyam8/periodic-pr-labeler/pkg/repository"
+
+	"github.com/google/go-github/v45/github"
+)
+
+type explainCommand struct {
+	Args struct {
+		Paths []string `positional-arg-name:"PATH" description:"Changed file path"`
+	} `positional-args:"yes"`
+}
+
+// runExplain prints the match evidence of the labels for the pull requests or the local file paths. Local paths with
+// a local mappings file need neither a token nor a repository. Local paths have no line counts, size labels are not
+// matched for them.
+func runExplain(opts options, w io.Writer) error {
+	paths := opts.Explain.Args.Paths
+	if len(paths) == 0 && len(opts.PullRequests) == 0 {
+		return errors.New("explain: no pull request or file paths given")
+	}
+	if len(paths) > 0 && opts.LabelMappingsLocal != "" {
+		ms, err := mappings.FromFile(opts.LabelMappingsLocal)
+		if err != nil {
+			return fmt.Errorf("label mappings: %v", err)
+		}
+		return explain(w, "paths", ms.WithoutSizeLabels(), &github.PullRequest{}, commitFiles(paths))
+	}
+
+	if err := validateOptions(opts); err != nil {
+		return err
+	}
+	rs, err := singleRepositoryService(opts)
+	if err != nil {
+		return err
+	}
+	ms, err := newMappingsService(opts, rs)
+	if err != nil {
+		return fmt.Errorf("label mappings: %v", err)
+	}
+	if len(paths) > 0 {
+		return explain(w, "paths", ms.WithoutSizeLabels(), &github.PullRequest{}, commitFiles(paths))
+	}
+
+	for _, number := range opts.PullRequests {
+		pull, err := rs.PullRequest(number)
+		if err != nil {
+			return fmt.Errorf("getting PR#%d: %v", number, err)
+		}
+		files, _, err := rs.PullRequestModifiedFiles(pull)
+		if err != nil {
+			return fmt.Errorf("getting PR#%d files: %v", number, err)
+		}
+		title := fmt.Sprintf("PR %s/%s#%d", rs.Owner(), rs.Name(), number)
+		if err := explain(w, title, ms, pull, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// singleRepositoryService returns the repository service for the only configured repository.
+func singleRepositoryService(opts options) (*repository.Repository, error) {
+	rs, err := newRepositoryService(opts)
+	if err != nil {
+		return nil, err
+	}
+	slugs, err := repositorySlugs(opts, rs)
+	if err != nil {
+		return nil, err
+	}
+	if len(slugs) != 1 {
+		return nil, fmt.Errorf("a single repository is required, got %d", len(slugs))
+	}
+	owner, name, _ := extractOwnerName(slugs[0])
+	return rs.WithRepository(owner, name), nil
+}
+
+// explain prints the matched labels with the files and the patterns that matched them, and the labels that negated
+// patterns excluded files from.
+func explain(w io.Writer, title string, ms *mappings.Mappings, pull *github.PullRequest, files []*github.CommitFile) error {
+	evs, err := ms.MatchedLabelsEvidence(pull, files)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, ev := range evs {
+		if !ev.Matched && len(ev.Matches) == 0 && len(ev.Excluded) == 0 {
+			continue
+		}
+		status := "matched"
+		if !ev.Matched {
+			status = "not matched"
+		}
+		fmt.Fprintf(w, "  %s: %s\n", ev.Label, status)
+		for _, m := range ev.Matches {
+			fmt.Fprintf(w, "    + %s (%s)\n", m.File, m.Pattern)
+		}
+		for _, m := range ev.Excluded {
+			fmt.Fprintf(w, "    - %s (excluded by %s)\n", m.File, m.Pattern)
+		}
+	}
+	return nil
+}
+
+func commitFiles(paths []string) []*github.CommitFile {
+	files := make([]*github.CommitFile, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, &github.CommitFile{Filename: github.String(path)})
+	}
+	return files
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/ilyam8/periodic-pr-labeler/pkg/mappings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type lintCommand struct {
+	Checkout string `long:"checkout" description:"Local checkout to check the patterns against (default: the repository default branch)"`
+}
+
+// runLint prints the problems of the mappings, it fails if any of them is not a warning. The patterns are checked
+// against the files of the local checkout, or of the repository default branch. A local mappings file without
+// a checkout or a repository is linted without checking the patterns against the files.
+func runLint(opts options, w io.Writer) error {
+	var ms *mappings.Mappings
+	var tree []string
+	var err error
+	if opts.LabelMappingsLocal != "" && (opts.Lint.Checkout != "" || len(opts.RepoSlugs) == 0 && opts.RepoList == "") {
+		if ms, err = mappings.FromFile(opts.LabelMappingsLocal); err != nil {
+			return fmt.Errorf("label mappings: %v", err)
+		}
+	} else {
+		if err := validateOptions(opts); err != nil {
+			return err
+		}
+		rs, err := singleRepositoryService(opts)
+		if err != nil {
+			return err
+		}
+		if ms, err = newMappingsService(opts, rs); err != nil {
+			return fmt.Errorf("label mappings: %v", err)
+		}
+		if opts.Lint.Checkout == "" {
+			var truncated bool
+			if tree, truncated, err = rs.Tree(); err != nil {
+				return fmt.Errorf("getting %s/%s files: %v", rs.Owner(), rs.Name(), err)
+			}
+			if truncated {
+				log.Warnf("%s/%s file list is truncated, patterns are not checked against the files", rs.Owner(), rs.Name())
+				tree = nil
+			}
+		}
+	}
+	if opts.Lint.Checkout != "" {
+		if tree, err = gitFiles(opts.Lint.Checkout); err != nil {
+			return err
+		}
+	}
+	if tree == nil {
+		log.Info("no checkout or repository files, patterns are not checked against the files")
+	}
+
+	problems := ms.Lint(tree)
+	var errs int
+	for _, p := range problems {
+		level := "warning"
+		if !p.Warning {
+			level = "error"
+			errs++
+		}
+		fmt.Fprintf(w, "%s: %s\n", level, p)
+	}
+	if errs > 0 {
+		return fmt.Errorf("lint: %d problem(s) found", errs)
+	}
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "no problems found")
+	}
+	return nil
+}
+
+// gitFiles lists the files tracked in the git checkout.
+func gitFiles(dir string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "-C", dir, "ls-files", "-z")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	files := []string{}
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
@@ -2,112 +2,236 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/ilyam8/periodic-pr-labeler/pkg/labeling"
 	"github.com/ilyam8/periodic-pr-labeler/pkg/mappings"
+	"github.com/ilyam8/periodic-pr-labeler/pkg/report"
 	"github.com/ilyam8/periodic-pr-labeler/pkg/repository"
+	"github.com/ilyam8/periodic-pr-labeler/pkg/state"
 
 	"github.com/jessevdk/go-flags"
 	log "github.com/sirupsen/logrus"
 )
 
 type options struct {
-	RepoSlug           string `short:"r" long:"repository" description:"GitHub repository slug"`
-	Token              string `short:"t" long:"token" description:"GitHub token"`
-	LabelMappings      string `short:"m" long:"label-mappings" description:"Label mappings file on github"`
-	LabelMappingsLocal string `short:"M" long:"label-mappings-local" description:"Label mappings file on the local system"`
-	DryRun             bool   `short:"d" long:"dry-run" description:"Dry run, labels won't be applied, only reported"`
+	RepoSlugs          []string      `short:"r" long:"repository" description:"GitHub repository slug, 'owner/*' for all organization repositories (repeatable)"`
+	RepoList           string        `short:"R" long:"repository-list" description:"File with GitHub repository slugs, one per line"`
+	Token              string        `short:"t" long:"token" description:"GitHub token"`
+	AppID              int64         `long:"app-id" description:"GitHub App ID, authenticate as a GitHub App installation instead of token"`
+	AppInstallationID  int64         `long:"app-installation-id" description:"GitHub App installation ID"`
+	AppPrivateKey      string        `long:"app-private-key" description:"GitHub App private key file"`
+	GitHubURL          string        `short:"u" long:"github-url" description:"GitHub API URL (default: https://api.github.com)"`
+	LabelMappings      string        `short:"m" long:"label-mappings" description:"Label mappings file on github"`
+	LabelMappingsLocal string        `short:"M" long:"label-mappings-local" description:"Label mappings file on the local system"`
+	DryRun             bool          `short:"d" long:"dry-run" description:"Dry run, labels won't be applied, only reported"`
+	Sync               bool          `short:"s" long:"sync" description:"Sync mode, managed labels that no longer match are removed"`
+	Concurrency        int           `short:"c" long:"concurrency" default:"1" description:"Number of pull requests processed in parallel"`
+	PerPage            int           `long:"per-page" default:"100" description:"Page size for GitHub list requests (max 100)"`
+	MaxRetries         int           `long:"max-retries" default:"5" description:"Number of retries of rate limited and failed GitHub requests"`
+	SkipDrafts         bool          `long:"skip-drafts" description:"Skip draft pull requests"`
+	BaseBranches       []string      `short:"b" long:"base-branch" description:"Label only pull requests targeting the base branch (repeatable)"`
+	OptOutLabels       []string      `long:"opt-out-label" description:"Skip pull requests that have the label, f.e. 'no-autolabel' (repeatable)"`
+	UpdatedWithin      time.Duration `long:"updated-within" description:"Label only pull requests updated within the duration, f.e. '24h'"`
+	StateFile          string        `long:"state-file" description:"File to remember labeled pull requests in, unchanged pull requests are skipped"`
+	PullRequests       []int         `long:"pr" description:"Label only the pull request, closed ones included (repeatable)"`
+	PullRequestEvent   bool          `long:"pr-from-event" description:"Label only the pull request of the GitHub Actions event (GITHUB_EVENT_PATH)"`
+	Report             string        `long:"report" choice:"json" choice:"markdown" description:"Write a report of labeled pull requests in the format"`
+	ReportFile         string        `long:"report-file" description:"Report file (default: stdout)"`
+
+	Explain explainCommand `command:"explain" description:"Explain labels of the pull requests (--pr) or of local file paths"`
+	Test    testCommand    `command:"test" description:"Print labels the local mappings file (-M) applies to changed file paths"`
+	Verify  verifyCommand  `command:"verify" description:"Run test cases of the local mappings file (-M)"`
+	Lint    lintCommand    `command:"lint" description:"Report mistakes in the mappings, f.e. patterns that match no file"`
 }
 
 func validateOptions(opts options) error {
-	if opts.RepoSlug == "" {
+	if len(opts.RepoSlugs) == 0 && opts.RepoList == "" {
 		return errors.New("repository slug config parameter not set")
 	}
-	if opts.Token == "" {
+	if opts.AppID != 0 {
+		if opts.AppInstallationID == 0 {
+			return errors.New("GitHub App installation ID config parameter not set")
+		}
+		if opts.AppPrivateKey == "" {
+			return errors.New("GitHub App private key config parameter not set")
+		}
+	} else if opts.Token == "" {
 		return errors.New("token config parameter not set")
 	}
 	if opts.LabelMappingsLocal == "" && opts.LabelMappings == "" {
 		return errors.New("label mappings config parameter not set")
 	}
+	if opts.Concurrency < 1 {
+		return errors.New("concurrency config parameter must be positive")
+	}
+	if opts.UpdatedWithin < 0 {
+		return errors.New("updated within config parameter must not be negative")
+	}
 	return nil
 }
 
-func parseCLI() options {
-	var opts options
+// parseCLI parses the command line, command is the name of the subcommand, empty if not given.
+func parseCLI() (opts options, command string) {
 	parser := flags.NewParser(&opts, flags.Default)
 	parser.Name = "labeler"
 	parser.Usage = "[OPTION]..."
+	parser.SubcommandsOptional = true
 
-	if _, err := parser.ParseArgs(os.Args); err != nil {
+	if _, err := parser.ParseArgs(os.Args[1:]); err != nil {
 		if flagsErr, ok := err.(*flags.Error); ok && flagsErr.Type == flags.ErrHelp {
 			os.Exit(0)
 		}
 		os.Exit(1)
 	}
-	return opts
+	if parser.Active != nil {
+		command = parser.Active.Name
+	}
+	return opts, command
 }
 
 func applyFromEnv(opts *options) {
-	if repoSlug, ok := os.LookupEnv("GITHUB_REPOSITORY"); ok && opts.RepoSlug == "" {
-		opts.RepoSlug = repoSlug
+	if repoSlug, ok := os.LookupEnv("GITHUB_REPOSITORY"); ok && len(opts.RepoSlugs) == 0 && opts.RepoList == "" {
+		opts.RepoSlugs = []string{repoSlug}
 	}
 	if token, ok := os.LookupEnv("GITHUB_TOKEN"); ok && opts.Token == "" {
 		opts.Token = token
 	}
+	if appID, ok := os.LookupEnv("GITHUB_APP_ID"); ok && opts.AppID == 0 {
+		opts.AppID, _ = strconv.ParseInt(appID, 10, 64)
+	}
+	if installationID, ok := os.LookupEnv("GITHUB_APP_INSTALLATION_ID"); ok && opts.AppInstallationID == 0 {
+		opts.AppInstallationID, _ = strconv.ParseInt(installationID, 10, 64)
+	}
+	if privateKey, ok := os.LookupEnv("GITHUB_APP_PRIVATE_KEY_FILE"); ok && opts.AppPrivateKey == "" {
+		opts.AppPrivateKey = privateKey
+	}
+	if githubURL, ok := os.LookupEnv("GITHUB_API_URL"); ok && opts.GitHubURL == "" {
+		opts.GitHubURL = githubURL
+	}
 	if labelMappings, ok := os.LookupEnv("LABEL_MAPPINGS_FILE"); ok && opts.LabelMappings == "" {
 		opts.LabelMappings = labelMappings
 	}
-}
-
-func extractOwnerName(repoSlug string) (owner, name string, ok bool) {
-	parts := strings.Split(strings.TrimSpace(repoSlug), "/")
-	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-		return "", "", false
+	if stateFile, ok := os.LookupEnv("LABELER_STATE_FILE"); ok && opts.StateFile == "" {
+		opts.StateFile = stateFile
 	}
-	return parts[0], parts[1], true
 }
 
-func newRepositoryService(opts options) *repository.Repository {
-	owner, name, ok := extractOwnerName(opts.RepoSlug)
-	if !ok {
-		log.Fatalf("repository slug config parameter bad syntax ('%s')", opts.RepoSlug)
-	}
+func newRepositoryService(opts options) (*repository.Repository, error) {
 	conf := repository.Config{
-		Owner: owner,
-		Name:  name,
-		Token: opts.Token,
+		Token:          opts.Token,
+		AppID:          opts.AppID,
+		InstallationID: opts.AppInstallationID,
+		BaseURL:        opts.GitHubURL,
+		PerPage:        opts.PerPage,
+		MaxRetries:     opts.MaxRetries,
+	}
+	if opts.AppPrivateKey != "" {
+		key, err := os.ReadFile(opts.AppPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("reading GitHub App private key: %v", err)
+		}
+		conf.PrivateKey = key
 	}
 	return repository.New(conf)
 }
 
-func newMappingsService(opts options, rs *repository.Repository) (ms *mappings.Mappings) {
-	var err error
+func newMappingsService(opts options, rs *repository.Repository) (ms *mappings.Mappings, err error) {
 	if opts.LabelMappingsLocal != "" {
 		ms, err = mappings.FromFile(opts.LabelMappingsLocal)
 	} else {
 		ms, err = mappings.FromGitHub(opts.LabelMappings, rs)
 	}
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	ms.Teams = rs
+	return ms, nil
+}
+
+func newStateService(opts options) (*state.Store, error) {
+	if opts.StateFile == "" {
+		return nil, nil
+	}
+	return state.Load(opts.StateFile)
+}
+
+func newReportService(opts options) *report.Report {
+	if opts.Report == "" {
+		return nil
 	}
-	return ms
+	return &report.Report{DryRun: opts.DryRun}
 }
 
-func newLabelingService(rs *repository.Repository, ms *mappings.Mappings, opts options) *labeling.Labeler {
+func newLabelingService(rs *repository.Repository, ms *mappings.Mappings, st *state.Store, rp *report.Report, opts options) *labeling.Labeler {
 	labSvc := labeling.New(rs, ms)
 	labSvc.DryRun = opts.DryRun
+	labSvc.Sync = opts.Sync
+	labSvc.Concurrency = opts.Concurrency
+	labSvc.SkipDrafts = opts.SkipDrafts
+	labSvc.BaseBranches = opts.BaseBranches
+	labSvc.OptOutLabels = opts.OptOutLabels
+	labSvc.UpdatedWithin = opts.UpdatedWithin
+	if st != nil {
+		labSvc.State = st
+	}
+	if rp != nil {
+		labSvc.Reporter = rp
+	}
 	return labSvc
 }
 
+func logRateLimit(rs *repository.Repository) {
+	rate, err := rs.RateLimit()
+	if err != nil {
+		log.Warnf("checking rate limit: %v", err)
+		return
+	}
+	log.Infof("GitHub API rate limit: %d of %d requests remaining, resets at %s",
+		rate.Remaining, rate.Limit, rate.Reset.Format(time.RFC3339))
+}
+
 func main() {
-	opts := parseCLI()
+	opts, command := parseCLI()
 	applyFromEnv(&opts)
 	if opts.LabelMappings == "" {
 		opts.LabelMappings = ".github/labeler.yml"
 	}
 
+	if opts.PullRequestEvent {
+		number, err := eventPullRequest()
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts.PullRequests = append(opts.PullRequests, number)
+	}
+
+	switch command {
+	case "explain":
+		if err := runExplain(opts, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "test":
+		if err := runTest(opts, os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "verify":
+		if err := runVerify(opts, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "lint":
+		if err := runLint(opts, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := validateOptions(opts); err != nil {
 		log.Fatal(err)
 	}
@@ -116,11 +240,43 @@ func main() {
 		log.SetLevel(log.DebugLevel)
 	}
 
-	repoSvc := newRepositoryService(opts)
-	mapSvc := newMappingsService(opts, repoSvc)
-	labSvc := newLabelingService(repoSvc, mapSvc, opts)
+	repoSvc, err := newRepositoryService(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slugs, err := repositorySlugs(opts, repoSvc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(opts.PullRequests) > 0 && len(slugs) != 1 {
+		log.Fatalf("pull request config parameter requires a single repository, got %d", len(slugs))
+	}
+	stateSvc, err := newStateService(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	reportSvc := newReportService(opts)
+
+	results := make([]repositoryResult, 0, len(slugs))
+	for _, slug := range slugs {
+		results = append(results, labelRepository(opts, repoSvc, stateSvc, reportSvc, slug))
+	}
+	logRateLimit(repoSvc)
+
+	if reportSvc != nil {
+		if err := writeReport(opts, reportSvc); err != nil {
+			log.Errorf("writing report: %v", err)
+		}
+	}
+
+	if stateSvc != nil {
+		if err := stateSvc.Save(); err != nil {
+			log.Errorf("saving state: %v", err)
+		}
+	}
 
-	if err := labSvc.ApplyLabels(); err != nil {
+	if err := summarize(results); err != nil {
 		log.Fatal(err)
 	}
 }
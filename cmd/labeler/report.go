@@ -0,0 +1,44 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/ilyam8/periodic-pr-labeler/pkg/report"
+)
+
+// writeReport writes the report to the report file or stdout. Under GitHub Actions the Markdown report is also
+// appended to the job summary (GITHUB_STEP_SUMMARY).
+func writeReport(opts options, rp *report.Report) error {
+	w := io.Writer(os.Stdout)
+	if opts.ReportFile != "" {
+		f, err := os.Create(opts.ReportFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	switch opts.Report {
+	case "json":
+		err = rp.WriteJSON(w)
+	case "markdown":
+		err = rp.WriteMarkdown(w)
+	}
+	if err != nil {
+		return err
+	}
+
+	path, ok := os.LookupEnv("GITHUB_STEP_SUMMARY")
+	if !ok || path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return rp.WriteMarkdown(f)
+}
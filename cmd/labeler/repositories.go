@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ilyam8/periodic-pr-labeler/pkg/labeling"
+	"github.com/ilyam8/periodic-pr-labeler/pkg/report"
+	"github.com/ilyam8/periodic-pr-labeler/pkg/repository"
+	"github.com/ilyam8/periodic-pr-labeler/pkg/state"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// repositorySlugs returns slugs of the repositories to label. Slugs come from the repository options and
+// the repository list file, 'owner/*' slugs are expanded to all the organization repositories.
+func repositorySlugs(opts options, rs *repository.Repository) ([]string, error) {
+	slugs := opts.RepoSlugs
+	if opts.RepoList != "" {
+		list, err := readRepositoryList(opts.RepoList)
+		if err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, list...)
+	}
+
+	var expanded []string
+	seen := make(map[string]bool)
+	for _, slug := range slugs {
+		owner, name, ok := extractOwnerName(slug)
+		if !ok {
+			return nil, fmt.Errorf("repository slug config parameter bad syntax ('%s')", slug)
+		}
+
+		list := []string{owner + "/" + name}
+		if name == "*" {
+			var err error
+			if list, err = rs.OrganizationRepositories(owner); err != nil {
+				return nil, fmt.Errorf("listing '%s' repositories: %v", owner, err)
+			}
+		}
+		for _, v := range list {
+			if !seen[v] {
+				seen[v] = true
+				expanded = append(expanded, v)
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// readRepositoryList reads repository slugs from a file, one per line. Empty lines and '#' comments are skipped.
+func readRepositoryList(filepath string) ([]string, error) {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var slugs []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			slugs = append(slugs, line)
+		}
+	}
+	return slugs, sc.Err()
+}
+
+func extractOwnerName(repoSlug string) (owner, name string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(repoSlug), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+type repositoryResult struct {
+	slug    string
+	summary labeling.Summary
+	err     error
+}
+
+func labelRepository(opts options, rs *repository.Repository, st *state.Store, rp *report.Report, slug string) repositoryResult {
+	owner, name, _ := extractOwnerName(slug)
+	rs = rs.WithRepository(owner, name)
+
+	log.Infof("labeling %s", slug)
+	ms, err := newMappingsService(opts, rs)
+	if err != nil {
+		return repositoryResult{slug: slug, err: fmt.Errorf("label mappings: %v", err)}
+	}
+
+	labSvc := newLabelingService(rs, ms, st, rp, opts)
+	var summary labeling.Summary
+	if len(opts.PullRequests) > 0 {
+		summary, err = labSvc.ApplyPullRequestsLabels(opts.PullRequests)
+	} else {
+		summary, err = labSvc.ApplyLabels()
+	}
+	return repositoryResult{slug: slug, summary: summary, err: err}
+}
+
+// summarize logs per repository summary and returns an error if labeling of any repository failed.
+func summarize(results []repositoryResult) error {
+	var failed []string
+	for _, res := range results {
+		entry := log.WithFields(log.Fields{
+			"pull_requests": res.summary.PullRequests,
+			"changed":       res.summary.Changed,
+			"failed":        res.summary.Failed,
+			"skipped":       res.summary.Skipped,
+		})
+		if res.err != nil {
+			failed = append(failed, res.slug)
+			entry.WithError(res.err).Errorf("summary %s", res.slug)
+			continue
+		}
+		entry.Infof("summary %s", res.slug)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to label %d of %d repositories: %s",
+			len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ilyam8/periodic-pr-labeler/pkg/mappings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+type testCommand struct {
+	Base string `long:"base" description:"Take changed files from 'git diff BASE...HEAD' in the local checkout"`
+	Head string `long:"head" default:"HEAD" description:"Head revision for --base"`
+	Args struct {
+		Paths []string `positional-arg-name:"PATH" description:"Changed file path (default: read from stdin, one per line)"`
+	} `positional-args:"yes"`
+}
+
+// runTest prints the labels the local mappings file applies to the changed files. It needs neither a token nor
+// network access. Size labels are matched only for 'git diff' files, file paths have no line counts.
+func runTest(opts options, stdin io.Reader, w io.Writer) error {
+	if opts.LabelMappingsLocal == "" {
+		return errors.New("test: local label mappings config parameter not set")
+	}
+	ms, err := mappings.FromFile(opts.LabelMappingsLocal)
+	if err != nil {
+		return fmt.Errorf("label mappings: %v", err)
+	}
+
+	var files []*github.CommitFile
+	switch cmd := opts.Test; {
+	case len(cmd.Args.Paths) > 0:
+		files = commitFiles(cmd.Args.Paths)
+		ms = ms.WithoutSizeLabels()
+	case cmd.Base != "":
+		if files, err = gitDiffFiles(cmd.Base, cmd.Head); err != nil {
+			return err
+		}
+	default:
+		paths, err := readPaths(stdin)
+		if err != nil {
+			return err
+		}
+		files = commitFiles(paths)
+		ms = ms.WithoutSizeLabels()
+	}
+
+	labels, err := ms.MatchedLabels(&github.PullRequest{}, files)
+	if err != nil {
+		return err
+	}
+	for _, label := range labels {
+		fmt.Fprintln(w, label)
+	}
+	return nil
+}
+
+// readPaths reads file paths, one per line. Empty lines are skipped.
+func readPaths(r io.Reader) ([]string, error) {
+	var paths []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if path := strings.TrimSpace(sc.Text()); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, sc.Err()
+}
+
+// gitDiffFiles returns the files changed between the merge base of base and head, and head, the way a pull request
+// diff does. Files carry the status and the number of changed lines, so status qualified patterns and size labels
+// match too.
+func gitDiffFiles(base, head string) ([]*github.CommitFile, error) {
+	out, err := gitDiff("--name-status", "-z", base+"..."+head)
+	if err != nil {
+		return nil, err
+	}
+	files, err := parseNameStatus(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if out, err = gitDiff("--numstat", "-z", base+"..."+head); err != nil {
+		return nil, err
+	}
+	changes, err := parseNumstat(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		file.Changes = github.Int(changes[file.GetFilename()])
+	}
+	return files, nil
+}
+
+func gitDiff(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"diff"}, args...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+var gitStatuses = map[byte]string{
+	'A': "added",
+	'C': "copied",
+	'D': "removed",
+	'M': "modified",
+	'R': "renamed",
+	'T': "changed",
+}
+
+// parseNameStatus parses 'git diff --name-status -z' output: NUL separated status and path fields, renames and
+// copies have both the previous and the new path.
+func parseNameStatus(out []byte) ([]*github.CommitFile, error) {
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	var files []*github.CommitFile
+	for i := 0; i < len(fields); i++ {
+		if fields[i] == "" {
+			continue
+		}
+		code := fields[i][0]
+		status, ok := gitStatuses[code]
+		if !ok {
+			return nil, fmt.Errorf("git diff: unknown status %q", fields[i])
+		}
+		file := &github.CommitFile{Status: github.String(status)}
+		if code == 'R' || code == 'C' {
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("git diff: unexpected output %q", fields[i:])
+			}
+			file.PreviousFilename = github.String(fields[i+1])
+			i++
+		} else if i+1 >= len(fields) {
+			return nil, fmt.Errorf("git diff: unexpected output %q", fields[i:])
+		}
+		file.Filename = github.String(fields[i+1])
+		i++
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// parseNumstat parses 'git diff --numstat -z' output into the number of changed lines per path. A record is added and
+// deleted lines and the path, renames and copies have an empty path followed by the previous and the new path fields.
+// Binary files count as 0 lines.
+func parseNumstat(out []byte) (map[string]int, error) {
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	changes := make(map[string]int)
+	for i := 0; i < len(fields); i++ {
+		if fields[i] == "" {
+			continue
+		}
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("git diff: unexpected numstat output %q", fields[i])
+		}
+		path := parts[2]
+		if path == "" {
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("git diff: unexpected numstat output %q", fields[i:])
+			}
+			path = fields[i+2]
+			i += 2
+		}
+		var n int
+		for _, v := range parts[:2] {
+			if v == "-" {
+				continue
+			}
+			lines, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("git diff: bad numstat line count %q", v)
+			}
+			n += lines
+		}
+		changes[path] = n
+	}
+	return changes, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	tests := map[string]struct {
+		out       string
+		wantFiles []*github.CommitFile
+		wantErr   bool
+	}{
+		"empty output": {out: ""},
+		"modified, added, removed": {
+			out: "M\x00main.go\x00A\x00docs/new.md\x00D\x00old.go\x00",
+			wantFiles: []*github.CommitFile{
+				{Status: github.String("modified"), Filename: github.String("main.go")},
+				{Status: github.String("added"), Filename: github.String("docs/new.md")},
+				{Status: github.String("removed"), Filename: github.String("old.go")},
+			},
+		},
+		"renamed and copied": {
+			out: "R100\x00old/a.go\x00new/a.go\x00C075\x00b.go\x00c.go\x00",
+			wantFiles: []*github.CommitFile{
+				{Status: github.String("renamed"), PreviousFilename: github.String("old/a.go"), Filename: github.String("new/a.go")},
+				{Status: github.String("copied"), PreviousFilename: github.String("b.go"), Filename: github.String("c.go")},
+			},
+		},
+		"type changed": {
+			out:       "T\x00link\x00",
+			wantFiles: []*github.CommitFile{{Status: github.String("changed"), Filename: github.String("link")}},
+		},
+		"path with spaces and newline": {
+			out:       "M\x00dir/a b\nc.md\x00",
+			wantFiles: []*github.CommitFile{{Status: github.String("modified"), Filename: github.String("dir/a b\nc.md")}},
+		},
+		"status without path": {out: "M\x00", wantErr: true},
+		"rename without path": {out: "R100\x00old.go\x00", wantErr: true},
+		"unknown status":      {out: "X\x00main.go\x00", wantErr: true},
+		"not NUL separated":   {out: "M\tmain.go\n", wantErr: true},
+		"path without status": {out: "main.go\x00", wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			files, err := parseNameStatus([]byte(test.out))
+
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.wantFiles, files)
+			}
+		})
+	}
+}
+
+func TestParseNumstat(t *testing.T) {
+	tests := map[string]struct {
+		out         string
+		wantChanges map[string]int
+		wantErr     bool
+	}{
+		"empty output": {out: "", wantChanges: map[string]int{}},
+		"modified and binary": {
+			out:         "3\t1\tmain.go\x00-\t-\tlogo.png\x00",
+			wantChanges: map[string]int{"main.go": 4, "logo.png": 0},
+		},
+		"renamed": {
+			out:         "2\t0\t\x00old/a.go\x00new/a.go\x0010\t5\tb.go\x00",
+			wantChanges: map[string]int{"new/a.go": 2, "b.go": 15},
+		},
+		"rename without path": {out: "2\t0\t\x00old/a.go\x00", wantErr: true},
+		"not NUL separated":   {out: "3 1 main.go\n", wantErr: true},
+		"bad line count":      {out: "x\t1\tmain.go\x00", wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			changes, err := parseNumstat([]byte(test.out))
+
+			if test.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.wantChanges, changes)
+			}
+		})
+	}
+}
+
+func TestGitDiffFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		require.NoErrorf(t, err, "git %v: %s", args, out)
+	}
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	git("init", "-q", "-b", "master")
+	write("main.go", "package main\n")
+	write("docs/old.md", "# Docs\n\nSome documentation that is long enough to be detected as a rename.\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	git("checkout", "-q", "-b", "feature")
+	write("main.go", "package main\n\nfunc main() {}\n")
+	require.NoError(t, os.Rename(filepath.Join(dir, "docs/old.md"), filepath.Join(dir, "docs/new.md")))
+	write("collectors/new.c", "int main;\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "feature")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() { _ = os.Chdir(wd) }()
+
+	files, err := gitDiffFiles("master", "feature")
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*github.CommitFile{
+		{Status: github.String("added"), Filename: github.String("collectors/new.c"), Changes: github.Int(1)},
+		{
+			Status:           github.String("renamed"),
+			PreviousFilename: github.String("docs/old.md"),
+			Filename:         github.String("docs/new.md"),
+			Changes:          github.Int(0),
+		},
+		{Status: github.String("modified"), Filename: github.String("main.go"), Changes: github.Int(2)},
+	}, files)
+
+	_, err = gitDiffFiles("master", "missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ilyam8/periodic-pr-labeler/pkg/mappings"
+)
+
+type verifyCommand struct {
+	Tests string `long:"tests" description:"Test cases file (default: '<mappings>.tests.yml' next to the mappings file, if exists)"`
+}
+
+// runVerify runs the test cases of the local mappings file: its 'tests' section and the test cases file.
+func runVerify(opts options, w io.Writer) error {
+	if opts.LabelMappingsLocal == "" {
+		return errors.New("verify: local label mappings config parameter not set")
+	}
+	ms, err := mappings.FromFile(opts.LabelMappingsLocal)
+	if err != nil {
+		return fmt.Errorf("label mappings: %v", err)
+	}
+
+	cases := ms.Tests()
+	path := opts.Verify.Tests
+	if path == "" {
+		path = sidecarTestsFile(opts.LabelMappingsLocal)
+		if _, err := os.Stat(path); err != nil {
+			path = ""
+		}
+	}
+	if path != "" {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		more, err := mappings.ParseTests(bs)
+		if err != nil {
+			return fmt.Errorf("'%s': %v", path, err)
+		}
+		cases = append(cases, more...)
+	}
+	if len(cases) == 0 {
+		return errors.New("verify: no test cases")
+	}
+
+	failures := ms.Verify(cases)
+	for _, f := range failures {
+		fmt.Fprintf(w, "FAIL %s\n", f.Case.Name)
+		if len(f.Case.Files) > 0 {
+			fmt.Fprintf(w, "  files: %s\n", strings.Join(f.Case.FileNames(), ", "))
+		}
+		if f.Err != nil {
+			fmt.Fprintf(w, "  error: %v\n", f.Err)
+		}
+		for _, label := range f.Missing {
+			fmt.Fprintf(w, "  - %s (expected, not matched)\n", label)
+		}
+		for _, label := range f.Unexpected {
+			fmt.Fprintf(w, "  + %s (matched, not expected)\n", label)
+		}
+	}
+	fmt.Fprintf(w, "%d of %d test cases passed\n", len(cases)-len(failures), len(cases))
+	if len(failures) > 0 {
+		return fmt.Errorf("verify: %d test case(s) failed", len(failures))
+	}
+	return nil
+}
+
+// sidecarTestsFile returns the default test cases file of the mappings file: 'labeler.yml' -> 'labeler.tests.yml'.
+func sidecarTestsFile(mappingsFile string) string {
+	ext := filepath.Ext(mappingsFile)
+	return strings.TrimSuffix(mappingsFile, ext) + ".tests" + ext
+}
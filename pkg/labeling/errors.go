@@ -0,0 +1,62 @@
+package labeling
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// PullRequestError is an error that occurred while labeling a single pull request.
+type PullRequestError struct {
+	Number int
+	Err    error
+}
+
+func (e *PullRequestError) Error() string {
+	return fmt.Sprintf("PR#%d: %v", e.Number, e.Err)
+}
+
+func (e *PullRequestError) Unwrap() error {
+	return e.Err
+}
+
+// PullRequestsError aggregates errors of all the pull requests that failed during a run.
+type PullRequestsError []*PullRequestError
+
+func (e PullRequestsError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("failed to label %d pull request(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+func newPullRequestsError(pulls []*github.PullRequest, res []pullResult) error {
+	var prErrs PullRequestsError
+	for i, r := range res {
+		if r.err != nil {
+			prErrs = append(prErrs, &PullRequestError{Number: pulls[i].GetNumber(), Err: r.err})
+		}
+	}
+	if len(prErrs) == 0 {
+		return nil
+	}
+	return prErrs
+}
+
+// isFatal reports whether the error affects all the pull requests, so there is no point to continue the run.
+func isFatal(err error) bool {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var respErr *github.ErrorResponse
+	switch {
+	case errors.As(err, &rateErr), errors.As(err, &abuseErr):
+		return true
+	case errors.As(err, &respErr):
+		return respErr.Response != nil && respErr.Response.StatusCode == http.StatusUnauthorized
+	}
+	return false
+}
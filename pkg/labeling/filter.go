@@ -0,0 +1,99 @@
+package labeling
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	log "github.com/sirupsen/logrus"
+)
+
+// filterPullRequests returns the pull requests that pass the filters and the number of skipped ones.
+func (l Labeler) filterPullRequests(pulls []*github.PullRequest, explicit bool) ([]*github.PullRequest, int) {
+	var filtered []*github.PullRequest
+	for _, pull := range pulls {
+		if reason := l.skipReason(pull, explicit); reason != "" {
+			if explicit {
+				log.WithField("skipped", reason).Info(l.fullName(pull))
+			} else {
+				log.WithField("skipped", reason).Debug(l.fullName(pull))
+			}
+			rep := l.newReport(pull.GetNumber())
+			rep.Skipped = reason
+			l.report(rep)
+			continue
+		}
+		filtered = append(filtered, pull)
+	}
+	return filtered, len(pulls) - len(filtered)
+}
+
+// skipReason returns why the pull request is skipped, or an empty string if it passes the filters. State doesn't skip
+// explicitly named pull requests.
+func (l Labeler) skipReason(pull *github.PullRequest, explicit bool) string {
+	if l.SkipDrafts && pull.GetDraft() {
+		return "draft"
+	}
+	if base := pull.GetBase().GetRef(); len(l.BaseBranches) > 0 && !contains(l.BaseBranches, base) {
+		return fmt.Sprintf("base branch '%s'", base)
+	}
+	for _, label := range pull.Labels {
+		if containsFold(l.OptOutLabels, label.GetName()) {
+			return fmt.Sprintf("opt-out label '%s'", label.GetName())
+		}
+	}
+	if !explicit && l.State != nil && l.State.Unchanged(l.repoSlug(), pull.GetNumber(), pull.GetHead().GetSHA(), pullHash(pull), l.Hash()) {
+		return "unchanged since the previous run"
+	}
+	return ""
+}
+
+// recordState remembers the labeled pull request. Nothing is recorded in dry run mode, labels aren't applied.
+func (l Labeler) recordState(pull *github.PullRequest) {
+	if l.State == nil || l.DryRun || pull.GetHead().GetSHA() == "" {
+		return
+	}
+	l.State.Record(l.repoSlug(), pull.GetNumber(), pull.GetHead().GetSHA(), pullHash(pull), l.Hash())
+}
+
+// pullHash is the hash of the pull request fields the mappings can match besides the files: a retitled or retargeted
+// pull request is labeled again without a new push.
+func pullHash(pull *github.PullRequest) string {
+	h := sha256.New()
+	for _, v := range []string{
+		pull.GetTitle(),
+		pull.GetBody(),
+		pull.GetBase().GetRef(),
+		pull.GetHead().GetRef(),
+		pull.GetUser().GetLogin(),
+	} {
+		// the separator keeps "ab"+"c" and "a"+"bc" apart
+		fmt.Fprintf(h, "%d:%s", len(v), v)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (l Labeler) repoSlug() string {
+	return l.Owner() + "/" + l.Name()
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold is contains for label names, they are case-insensitive.
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
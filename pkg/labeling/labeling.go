@@ -1,26 +1,63 @@
 package labeling
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	log "github.com/sirupsen/logrus"
 )
 
 type Repository interface {
-	OpenPullRequests() ([]*github.PullRequest, error)
-	PullRequestModifiedFiles(number int) ([]*github.CommitFile, error)
+	OpenPullRequests(since time.Time) ([]*github.PullRequest, error)
+	PullRequest(number int) (*github.PullRequest, error)
+	PullRequestModifiedFiles(pull *github.PullRequest) (files []*github.CommitFile, truncated bool, err error)
 	AddLabelsToPullRequest(number int, labels []string) error
+	RemoveLabelFromPullRequest(number int, label string) error
+	Labels() ([]*github.Label, error)
+	CreateLabel(label *github.Label) error
+	EditLabel(label *github.Label) error
 	Owner() string
 	Name() string
 }
 
 type Mappings interface {
-	MatchedLabels([]*github.CommitFile) (labels []string)
+	MatchedLabels(pull *github.PullRequest, files []*github.CommitFile) (labels []string, err error)
+	Managed(label string) bool
+	Removable(label string) bool
+	LabelDefinitions() []*github.Label
+	MatchedFiles(label string, files []*github.CommitFile) []string
+	Hash() string
+}
+
+// State remembers the pull requests labeled by the previous runs. pullHash is the hash of the pull request fields
+// the mappings can match besides the files.
+type State interface {
+	Unchanged(repo string, number int, headSHA, pullHash, mappingsHash string) bool
+	Record(repo string, number int, headSHA, pullHash, mappingsHash string)
 }
 
 type Labeler struct {
 	DryRun bool
+	// Sync enables removal of all managed labels that no longer match.
+	Sync bool
+	// Concurrency is the number of pull requests processed in parallel.
+	Concurrency int
+	// SkipDrafts skips draft pull requests.
+	SkipDrafts bool
+	// BaseBranches limits labeling to pull requests targeting any of the branches, all branches if empty.
+	BaseBranches []string
+	// OptOutLabels skips pull requests that have any of the labels.
+	OptOutLabels []string
+	// UpdatedWithin limits labeling to pull requests updated within the duration, all pull requests if zero.
+	UpdatedWithin time.Duration
+	// State skips pull requests which head commit, fields and mappings haven't changed since the previous run, if set.
+	State State
+	// Reporter receives the result of each pull request, if set.
+	Reporter Reporter
 	Repository
 	Mappings
 }
@@ -32,46 +69,279 @@ func New(r Repository, m Mappings) *Labeler {
 	}
 }
 
-func (l Labeler) ApplyLabels() (err error) {
-	pulls, err := l.OpenPullRequests()
+// Summary is the outcome of a labeling run.
+type Summary struct {
+	// PullRequests is the number of processed pull requests.
+	PullRequests int
+	// Changed is the number of pull requests which labels were changed (or would be changed in dry run mode).
+	Changed int
+	// Failed is the number of pull requests that failed to be labeled.
+	Failed int
+	// Skipped is the number of pull requests skipped by the filters.
+	Skipped int
+}
+
+func (l Labeler) ApplyLabels() (Summary, error) {
+	if err := l.syncRepositoryLabels(); err != nil {
+		return Summary{}, err
+	}
+
+	var since time.Time
+	if l.UpdatedWithin > 0 {
+		since = time.Now().Add(-l.UpdatedWithin)
+	}
+	pulls, err := l.OpenPullRequests(since)
 	if err != nil {
-		return err
+		return Summary{}, err
 	}
 	log.Debugf("found %d open pull requests", len(pulls))
-	return l.applyLabels(pulls)
+	return l.applyFilteredLabels(pulls, false)
 }
 
-func (l Labeler) applyLabels(pulls []*github.PullRequest) error {
-	for _, pull := range pulls {
-		files, err := l.PullRequestModifiedFiles(pull.GetNumber())
+// ApplyPullRequestsLabels labels the pull requests with the numbers. Pull requests are fetched individually,
+// closed pull requests are labeled too. The pull requests are labeled even if State reports them unchanged.
+// A pull request that fails to be fetched doesn't stop the others.
+func (l Labeler) ApplyPullRequestsLabels(numbers []int) (Summary, error) {
+	if err := l.syncRepositoryLabels(); err != nil {
+		return Summary{}, err
+	}
+
+	var (
+		pulls  = make([]*github.PullRequest, 0, len(numbers))
+		failed PullRequestsError
+	)
+	for _, number := range numbers {
+		pull, err := l.PullRequest(number)
 		if err != nil {
-			return err
+			log.WithError(err).Errorf("PR %s/%s#%d: getting pull request", l.Owner(), l.Name(), number)
+			failed = append(failed, &PullRequestError{Number: number, Err: fmt.Errorf("getting pull request: %w", err)})
+			if l.Reporter != nil {
+				rep := l.newReport(number)
+				rep.Error = err.Error()
+				l.report(rep)
+			}
+			if isFatal(err) {
+				return Summary{PullRequests: len(failed), Failed: len(failed)}, failed
+			}
+			continue
 		}
+		pulls = append(pulls, pull)
+	}
 
-		expected := l.MatchedLabels(files)
-		if len(expected) == 0 {
-			log.WithField("labels", "no match").Info(l.fullName(pull))
-			continue
+	summary, err := l.applyFilteredLabels(pulls, true)
+	if len(failed) == 0 {
+		return summary, err
+	}
+	summary.PullRequests += len(failed)
+	summary.Failed += len(failed)
+	var prErrs PullRequestsError
+	if errors.As(err, &prErrs) {
+		failed = append(failed, prErrs...)
+	}
+	return summary, failed
+}
+
+// applyFilteredLabels labels the pull requests that pass the filters. explicit is set for the pull requests named
+// by the user: their skips are logged at info level and State doesn't skip them.
+func (l Labeler) applyFilteredLabels(pulls []*github.PullRequest, explicit bool) (Summary, error) {
+	pulls, skipped := l.filterPullRequests(pulls, explicit)
+	summary, err := l.applyLabels(pulls)
+	summary.Skipped = skipped
+	return summary, err
+}
+
+func (l Labeler) applyLabels(pulls []*github.PullRequest) (Summary, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		jobs = make(chan int)
+		logs = newOrderedLogs(len(pulls))
+		res  = make([]pullResult, len(pulls))
+	)
+
+	for i := 0; i < l.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				logger, buf := newBufferedLogger()
+				rep := l.newReport(pulls[i].GetNumber())
+				changed, err := l.applyPullRequestLabels(ctx, pulls[i], logger, &rep)
+				if !errors.Is(err, context.Canceled) {
+					res[i] = pullResult{processed: true, changed: changed, err: err, report: rep}
+				}
+				if err == nil {
+					l.recordState(pulls[i])
+				}
+				if res[i].err != nil {
+					logger.WithError(err).Error(l.fullName(pulls[i]))
+					if isFatal(err) {
+						cancel()
+					}
+				}
+				logs.done(i, buf)
+			}
+		}()
+	}
+
+dispatch:
+	for i := range pulls {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case jobs <- i:
 		}
+	}
+	close(jobs)
+	wg.Wait()
+	logs.flush()
+
+	l.reportResults(res)
+
+	return summarize(res), newPullRequestsError(pulls, res)
+}
 
-		if !shouldAddLabels(expected, pull.Labels) {
-			log.WithField("labels", "has all").Debug(l.fullName(pull))
+type pullResult struct {
+	processed bool
+	changed   bool
+	err       error
+	report    PullRequestReport
+}
+
+func summarize(res []pullResult) Summary {
+	var summary Summary
+	for _, r := range res {
+		if !r.processed {
 			continue
 		}
+		summary.PullRequests++
+		switch {
+		case r.err != nil:
+			summary.Failed++
+		case r.changed:
+			summary.Changed++
+		}
+	}
+	return summary
+}
 
-		log.WithField("labels", expected).Debugf("%s [dry run]", l.fullName(pull))
-		if l.DryRun {
-			continue
+func (l Labeler) workers() int {
+	if l.Concurrency < 1 {
+		return 1
+	}
+	return l.Concurrency
+}
+
+// applyPullRequestLabels adds expected and removes stale labels of a single pull request.
+// changed reports whether the pull request labels needed a change.
+func (l Labeler) applyPullRequestLabels(ctx context.Context, pull *github.PullRequest, logger log.FieldLogger, rep *PullRequestReport) (changed bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	files, truncated, err := l.PullRequestModifiedFiles(pull)
+	if err != nil {
+		return false, err
+	}
+	if truncated {
+		logger.Warnf("%s: list of changed files is truncated (%d files), labels may be incomplete", l.fullName(pull), len(files))
+	}
+
+	expected, err := l.MatchedLabels(pull, files)
+	if err != nil {
+		return false, err
+	}
+	stale := l.staleLabels(expected, pull.Labels)
+	// a stale label may still match a file past the end of the truncated list
+	if truncated && len(stale) > 0 {
+		logger.WithField("labels", stale).Warnf("%s: list of changed files is truncated, not removing labels", l.fullName(pull))
+		stale = nil
+	}
+	add := shouldAddLabels(expected, pull.Labels)
+
+	rep.Labels = expected
+	for _, name := range expected {
+		if matched := l.MatchedFiles(name, files); len(matched) > 0 {
+			if rep.Files == nil {
+				rep.Files = make(map[string][]string)
+			}
+			rep.Files[name] = matched
 		}
+	}
+	rep.Added = difference(expected, pull.Labels)
+	rep.Removed = stale
+
+	switch {
+	case len(expected) == 0 && len(stale) == 0:
+		logger.WithField("labels", "no match").Info(l.fullName(pull))
+		return false, nil
+	case !add && len(stale) == 0:
+		logger.WithField("labels", "has all").Debug(l.fullName(pull))
+		return false, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if add {
+		if err := l.addLabels(pull, expected, logger); err != nil {
+			return true, err
+		}
+	}
+	return true, l.removeLabels(pull, stale, logger)
+}
+
+func (l Labeler) addLabels(pull *github.PullRequest, labels []string, logger log.FieldLogger) error {
+	logger.WithField("labels", labels).Debugf("%s [dry run]", l.fullName(pull))
+	if l.DryRun {
+		return nil
+	}
+
+	logger.WithField("labels", labels).Infof("%s [applying]", l.fullName(pull))
+	return l.AddLabelsToPullRequest(pull.GetNumber(), labels)
+}
+
+func (l Labeler) removeLabels(pull *github.PullRequest, labels []string, logger log.FieldLogger) error {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	logger.WithField("labels", labels).Debugf("%s [dry run, removing]", l.fullName(pull))
+	if l.DryRun {
+		return nil
+	}
 
-		log.WithField("labels", expected).Infof("%s [applying]", l.fullName(pull))
-		if err := l.AddLabelsToPullRequest(pull.GetNumber(), expected); err != nil {
+	logger.WithField("labels", labels).Infof("%s [removing]", l.fullName(pull))
+	for _, name := range labels {
+		if err := l.RemoveLabelFromPullRequest(pull.GetNumber(), name); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// staleLabels returns the pull request labels that are managed by the mappings, allowed to be removed
+// and no longer expected.
+func (l Labeler) staleLabels(expected []string, existing []*github.Label) []string {
+	expectedSet := make(map[string]struct{}, len(expected))
+	for _, v := range expected {
+		expectedSet[v] = struct{}{}
+	}
+	var stale []string
+	for _, v := range existing {
+		name := v.GetName()
+		if _, ok := expectedSet[name]; ok || !l.Managed(name) {
+			continue
+		}
+		if l.Sync || l.Removable(name) {
+			stale = append(stale, name)
+		}
+	}
+	return stale
+}
+
 func (l Labeler) fullName(pull *github.PullRequest) string {
 	return fmt.Sprintf("PR %s/%s#%d", l.Owner(), l.Name(), pull.GetNumber())
 }
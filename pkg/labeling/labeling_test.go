@@ -1,7 +1,9 @@
 package labeling
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	"github.com/stretchr/testify/assert"
@@ -12,10 +14,13 @@ const open = "open"
 
 func closePR(pr pullRequest) pullRequest { pr.state = ""; return pr }
 
+func withLabels(pr pullRequest, labels ...string) pullRequest { pr.labels = labels; return pr }
+
 type pullRequest struct {
-	title string
-	state string
-	files []string
+	title  string
+	state  string
+	files  []string
+	labels []string
 
 	*github.PullRequest
 }
@@ -73,9 +78,219 @@ func TestLabeler_ApplyLabels(t *testing.T) {
 
 	labeler, _ := prepareApplyLabelsLabeler(tests)
 
-	err := labeler.ApplyLabels()
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	ensurePullRequestsHaveExpectedLabels(t, tests)
+}
+
+func TestLabeler_ApplyLabels_Concurrently(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin, expectedLabels: []string{"collectors"}},
+		{pullRequest: prModifyPythonExample, expectedLabels: []string{"collectors", "python.d"}},
+		{pullRequest: prModifyPythonApache, expectedLabels: []string{"collectors", "python.d", "python.d/apache"}},
+		{pullRequest: prModifyBashExample, expectedLabels: []string{"collectors", "charts.d"}},
+		{pullRequest: prModifyBashApache, expectedLabels: []string{"collectors", "charts.d", "charts.d/apache"}},
+		{pullRequest: prClosedModifyBashTomcat},
+	}
+
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	labeler.Concurrency = 3
+
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	ensurePullRequestsHaveExpectedLabels(t, tests)
+}
+
+func TestLabeler_ApplyLabels_Summary(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: withLabels(prModifyAppsPlugin, "collectors")},
+		{pullRequest: prModifyPythonExample},
+		{pullRequest: prModifyBashExample},
+		{pullRequest: prClosedModifyBashTomcat},
+	}
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	rs.addLabelsErrs[tests[2].GetNumber()] = errors.New("mock AddLabelsToPullRequest error")
+
+	summary, err := labeler.ApplyLabels()
+
+	assert.Error(t, err)
+	assert.Equal(t, Summary{PullRequests: 3, Changed: 1, Failed: 1}, summary)
+}
+
+func TestLabeler_ApplyPullRequestsLabels(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin},
+		{pullRequest: prModifyPythonExample, expectedLabels: []string{"collectors", "python.d"}},
+		{pullRequest: prModifyBashExample},
+		{pullRequest: prClosedModifyBashTomcat, expectedLabels: []string{"collectors", "charts.d"}},
+	}
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+
+	summary, err := labeler.ApplyPullRequestsLabels([]int{tests[1].GetNumber(), tests[3].GetNumber()})
+
+	require.NoError(t, err)
+	assert.Equal(t, Summary{PullRequests: 2, Changed: 2}, summary)
+	ensurePullRequestsHaveOnlyExpectedLabels(t, tests)
+}
+
+func TestLabeler_ApplyPullRequestsLabels_ContinuesIfPullRequestFails(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin, expectedLabels: []string{"collectors"}},
+	}
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	reporter := &mockReporter{}
+	labeler.Reporter = reporter
+
+	summary, err := labeler.ApplyPullRequestsLabels([]int{42, tests[0].GetNumber()})
+
+	var prErrs PullRequestsError
+	require.ErrorAs(t, err, &prErrs)
+	require.Len(t, prErrs, 1)
+	assert.Equal(t, 42, prErrs[0].Number)
+	assert.Equal(t, Summary{PullRequests: 2, Changed: 1, Failed: 1}, summary)
+	ensurePullRequestsHaveOnlyExpectedLabels(t, tests)
+	require.Len(t, *reporter, 2)
+	assert.Equal(t, 42, (*reporter)[0].Number)
+	assert.NotEmpty(t, (*reporter)[0].Error)
+}
+
+func TestLabeler_ApplyPullRequestsLabels_IgnoresState(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin, expectedLabels: []string{"collectors"}},
+	}
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	state := mockState{}
+	labeler.State = state
+	tests[0].Head = &github.PullRequestBranch{SHA: github.String("sha1")}
+	state.Record(labeler.repoSlug(), tests[0].GetNumber(), "sha1", pullHash(tests[0].PullRequest), labeler.Hash())
+
+	summary, err := labeler.ApplyPullRequestsLabels([]int{tests[0].GetNumber()})
+
+	require.NoError(t, err)
+	assert.Equal(t, Summary{PullRequests: 1, Changed: 1}, summary)
+	ensurePullRequestsHaveOnlyExpectedLabels(t, tests)
+}
+
+func TestLabeler_ApplyLabels_Filters(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin, expectedLabels: []string{"collectors"}},
+		{pullRequest: prModifyPythonExample},
+		{pullRequest: prModifyPythonApache},
+		{pullRequest: withLabels(prModifyBashExample, "No-Autolabel")},
+		{pullRequest: prModifyBashApache},
+	}
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	labeler.SkipDrafts = true
+	labeler.BaseBranches = []string{"master", "release"}
+	labeler.OptOutLabels = []string{"no-autolabel"}
+	labeler.UpdatedWithin = 24 * time.Hour
+
+	updatedAgo := func(d time.Duration) *time.Time { t := time.Now().Add(-d); return &t }
+	for _, test := range tests {
+		test.Base = &github.PullRequestBranch{Ref: github.String("master")}
+		test.UpdatedAt = updatedAgo(time.Hour)
+	}
+	tests[1].Draft = github.Bool(true)
+	tests[2].Base.Ref = github.String("feature")
+	tests[4].UpdatedAt = updatedAgo(48 * time.Hour)
+
+	summary, err := labeler.ApplyLabels()
+
 	require.NoError(t, err)
+	assert.Equal(t, Summary{PullRequests: 1, Changed: 1, Skipped: 3}, summary)
+	ensurePullRequestsHaveOnlyExpectedLabels(t, tests[:3])
+}
+
+func TestLabeler_ApplyLabels_SkipsUnchangedPullRequests(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin, expectedLabels: []string{"collectors"}},
+		{pullRequest: prModifyPythonExample, expectedLabels: []string{"collectors", "python.d"}},
+		{pullRequest: prModifyBashExample, expectedLabels: []string{"collectors", "charts.d"}},
+	}
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	state := mockState{}
+	labeler.State = state
+	labeler.Mappings.(*mockMappings).hash = "hash1"
+	for _, test := range tests {
+		test.Head = &github.PullRequestBranch{SHA: github.String("sha1")}
+	}
+
+	summary, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	assert.Equal(t, Summary{PullRequests: 3, Changed: 3}, summary)
+	assert.Len(t, state, 3)
 	ensurePullRequestsHaveExpectedLabels(t, tests)
+
+	rs.modifiedFilesCalls.Store(0)
+	tests[1].Head.SHA = github.String("sha2")
+	summary, err = labeler.ApplyLabels()
+	require.NoError(t, err)
+	assert.Equal(t, Summary{PullRequests: 1, Skipped: 2}, summary)
+	assert.Equal(t, int32(1), rs.modifiedFilesCalls.Load())
+
+	tests[2].Title = github.String("fix: retitled")
+	tests[2].Base = &github.PullRequestBranch{Ref: github.String("release")}
+	summary, err = labeler.ApplyLabels()
+	require.NoError(t, err)
+	assert.Equal(t, Summary{PullRequests: 1, Skipped: 2}, summary)
+
+	labeler.Mappings.(*mockMappings).hash = "hash2"
+	summary, err = labeler.ApplyLabels()
+	require.NoError(t, err)
+	assert.Equal(t, Summary{PullRequests: 3}, summary)
+}
+
+func TestLabeler_ApplyLabels_DoesntRecordStateInDryRunMode(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin},
+	}
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	state := mockState{}
+	labeler.State = state
+	labeler.DryRun = true
+	tests[0].Head = &github.PullRequestBranch{SHA: github.String("sha1")}
+
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	assert.Empty(t, state)
+}
+
+func TestLabeler_ApplyLabels_Reports(t *testing.T) {
+	prModifyCollectorsReadme := pullRequest{title: "Modify collectors README", state: open, files: []string{"collectors/README.md"}}
+	tests := []applyLabelsTest{
+		{pullRequest: withLabels(prModifyAppsPlugin, "charts.d")},
+		{pullRequest: withLabels(prModifyBashExample, "no-autolabel")},
+		{pullRequest: prModifyCollectorsReadme},
+	}
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	labeler.Mappings.(*mockMappings).removable["charts.d"] = true
+	labeler.OptOutLabels = []string{"no-autolabel"}
+	rs.addLabelsErrs[tests[2].GetNumber()] = errors.New("mock AddLabelsToPullRequest error")
+	reporter := &mockReporter{}
+	labeler.Reporter = reporter
+
+	_, err := labeler.ApplyLabels()
+	require.Error(t, err)
+
+	assert.Equal(t, mockReporter{
+		{Repository: "owner/name", Number: tests[1].GetNumber(), Skipped: "opt-out label 'no-autolabel'"},
+		{
+			Repository: "owner/name",
+			Number:     tests[0].GetNumber(),
+			Labels:     []string{"collectors"},
+			Files:      map[string][]string{"collectors": {"collectors/apps.plugin/apps_plugin.c"}},
+			Added:      []string{"collectors"},
+			Removed:    []string{"charts.d"},
+		},
+		{
+			Repository: "owner/name",
+			Number:     tests[2].GetNumber(),
+			Labels:     []string{"collectors"},
+			Files:      map[string][]string{"collectors": {"collectors/README.md"}},
+			Added:      []string{"collectors"},
+			Error:      "mock AddLabelsToPullRequest error",
+		},
+	}, *reporter)
 }
 
 func TestLabeler_ApplyLabels_DoesntApplyLabelsInDryRunMode(t *testing.T) {
@@ -90,15 +305,117 @@ func TestLabeler_ApplyLabels_DoesntApplyLabelsInDryRunMode(t *testing.T) {
 	labeler, _ := prepareApplyLabelsLabeler(tests)
 	labeler.DryRun = true
 
-	err := labeler.ApplyLabels()
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	ensurePullRequestsHaveExpectedLabels(t, tests)
+}
+
+func TestLabeler_ApplyLabels_AppliesLabelsWhenFilesListIsTruncated(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyPythonApache, expectedLabels: []string{"collectors", "python.d", "python.d/apache"}},
+		{pullRequest: prModifyBashExample, expectedLabels: []string{"collectors", "charts.d"}},
+	}
+
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	rs.truncatedFiles = true
+
+	_, err := labeler.ApplyLabels()
 	require.NoError(t, err)
 	ensurePullRequestsHaveExpectedLabels(t, tests)
 }
 
+func TestLabeler_ApplyLabels_DoesntRemoveLabelsWhenFilesListIsTruncated(t *testing.T) {
+	tests := []applyLabelsTest{
+		{
+			pullRequest:    withLabels(prModifyAppsPlugin, "python.d", "charts.d"),
+			expectedLabels: []string{"collectors", "python.d", "charts.d"},
+		},
+	}
+
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	labeler.Sync = true
+	rs.truncatedFiles = true
+	reporter := &mockReporter{}
+	labeler.Reporter = reporter
+
+	summary, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	assert.Equal(t, Summary{PullRequests: 1, Changed: 1}, summary)
+	ensurePullRequestsHaveOnlyExpectedLabels(t, tests)
+	require.Len(t, *reporter, 1)
+	assert.Empty(t, (*reporter)[0].Removed)
+}
+
+func TestLabeler_ApplyLabels_RemovesStaleLabelsInSyncMode(t *testing.T) {
+	tests := []applyLabelsTest{
+		{
+			pullRequest:    withLabels(prModifyAppsPlugin, "collectors", "python.d", "bug"),
+			expectedLabels: []string{"collectors", "bug"},
+		},
+		{
+			pullRequest:    withLabels(prModifyPythonExample, "charts.d", "charts.d/apache"),
+			expectedLabels: []string{"collectors", "python.d"},
+		},
+	}
+
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	labeler.Sync = true
+
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	ensurePullRequestsHaveOnlyExpectedLabels(t, tests)
+}
+
+func TestLabeler_ApplyLabels_RemovesOnlyRemovableLabelsWithoutSyncMode(t *testing.T) {
+	tests := []applyLabelsTest{
+		{
+			pullRequest:    withLabels(prModifyAppsPlugin, "python.d", "charts.d", "bug"),
+			expectedLabels: []string{"collectors", "python.d", "bug"},
+		},
+	}
+
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	labeler.Mappings.(*mockMappings).removable["charts.d"] = true
+
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	ensurePullRequestsHaveOnlyExpectedLabels(t, tests)
+}
+
+func TestLabeler_ApplyLabels_DoesntRemoveLabelsInDryRunMode(t *testing.T) {
+	tests := []applyLabelsTest{
+		{
+			pullRequest:    withLabels(prModifyAppsPlugin, "collectors", "python.d"),
+			expectedLabels: []string{"collectors", "python.d"},
+		},
+	}
+
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	labeler.Sync = true
+	labeler.DryRun = true
+
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+	ensurePullRequestsHaveOnlyExpectedLabels(t, tests)
+}
+
+func TestLabeler_ApplyLabels_ReturnsErrorIfRemoveLabelFromPullRequestFails(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: withLabels(prModifyAppsPlugin, "collectors", "python.d")},
+	}
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	labeler.Sync = true
+	rs.errOnRemoveLabelFromPR = true
+
+	_, err := labeler.ApplyLabels()
+	assert.Error(t, err)
+}
+
 func TestLabeler_ApplyLabels_SuccessfulWhenZeroPullRequest(t *testing.T) {
 	labeler, _ := prepareApplyLabelsLabeler(nil)
 
-	assert.NoError(t, labeler.ApplyLabels())
+	_, err := labeler.ApplyLabels()
+	assert.NoError(t, err)
 }
 
 func TestLabeler_ApplyLabels_SuccessfulWhenZeroOpenPullRequest(t *testing.T) {
@@ -112,7 +429,8 @@ func TestLabeler_ApplyLabels_SuccessfulWhenZeroOpenPullRequest(t *testing.T) {
 
 	labeler, _ := prepareApplyLabelsLabeler(tests)
 
-	assert.NoError(t, labeler.ApplyLabels())
+	_, err := labeler.ApplyLabels()
+	assert.NoError(t, err)
 	ensurePullRequestsHaveExpectedLabels(t, tests)
 }
 
@@ -120,7 +438,8 @@ func TestLabeler_ApplyLabels_ReturnsErrorIfOpenPullRequestsFails(t *testing.T) {
 	labeler, rs := prepareApplyLabelsLabeler(nil)
 	rs.errOnOpenPullRequests = true
 
-	assert.Error(t, labeler.ApplyLabels())
+	_, err := labeler.ApplyLabels()
+	assert.Error(t, err)
 }
 
 func TestLabeler_ApplyLabels_ReturnsErrorIfPullRequestModifiedFilesFails(t *testing.T) {
@@ -130,7 +449,20 @@ func TestLabeler_ApplyLabels_ReturnsErrorIfPullRequestModifiedFilesFails(t *test
 	labeler, rs := prepareApplyLabelsLabeler(tests)
 	rs.errOnPullRequestModifiedFiles = true
 
-	assert.Error(t, labeler.ApplyLabels())
+	_, err := labeler.ApplyLabels()
+	assert.Error(t, err)
+}
+
+func TestLabeler_ApplyLabels_ReturnsErrorIfMatchedLabelsFails(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin},
+	}
+	labeler, _ := prepareApplyLabelsLabeler(tests)
+	labeler.Mappings.(*mockMappings).errOnMatchedLabels = true
+
+	_, err := labeler.ApplyLabels()
+	assert.Error(t, err)
+	ensurePullRequestsHaveExpectedLabels(t, tests)
 }
 
 func TestLabeler_ApplyLabels_ReturnsErrorIfAddLabelsToPullRequestFails(t *testing.T) {
@@ -140,7 +472,65 @@ func TestLabeler_ApplyLabels_ReturnsErrorIfAddLabelsToPullRequestFails(t *testin
 	labeler, rs := prepareApplyLabelsLabeler(tests)
 	rs.errOnAddLabelsToPullRequest = true
 
-	assert.Error(t, labeler.ApplyLabels())
+	_, err := labeler.ApplyLabels()
+	assert.Error(t, err)
+}
+
+func TestLabeler_ApplyLabels_ReturnsErrorIfAddLabelsToPullRequestFailsConcurrently(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin},
+		{pullRequest: prModifyPythonExample},
+		{pullRequest: prModifyPythonApache},
+		{pullRequest: prModifyBashExample},
+		{pullRequest: prModifyBashApache},
+	}
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	labeler.Concurrency = 2
+	rs.errOnAddLabelsToPullRequest = true
+
+	_, err := labeler.ApplyLabels()
+	assert.Error(t, err)
+}
+
+func TestLabeler_ApplyLabels_ContinuesWhenPullRequestFails(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin, expectedLabels: []string{"collectors"}},
+		{pullRequest: prModifyPythonExample},
+		{pullRequest: prModifyBashExample, expectedLabels: []string{"collectors", "charts.d"}},
+		{pullRequest: prModifyBashApache},
+	}
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	rs.addLabelsErrs[tests[1].GetNumber()] = errors.New("mock locked conversation error")
+	rs.addLabelsErrs[tests[3].GetNumber()] = errors.New("mock bad gateway error")
+
+	_, err := labeler.ApplyLabels()
+	require.Error(t, err)
+	ensurePullRequestsHaveExpectedLabels(t, tests)
+
+	var prErrs PullRequestsError
+	require.ErrorAs(t, err, &prErrs)
+	require.Len(t, prErrs, 2)
+	assert.Equal(t, tests[1].GetNumber(), prErrs[0].Number)
+	assert.Equal(t, tests[3].GetNumber(), prErrs[1].Number)
+}
+
+func TestLabeler_ApplyLabels_StopsOnFatalError(t *testing.T) {
+	tests := []applyLabelsTest{
+		{pullRequest: prModifyAppsPlugin},
+		{pullRequest: prModifyPythonExample},
+		{pullRequest: prModifyBashExample},
+	}
+	labeler, rs := prepareApplyLabelsLabeler(tests)
+	rs.addLabelsErrs[tests[0].GetNumber()] = &github.RateLimitError{Message: "mock rate limit error"}
+
+	_, err := labeler.ApplyLabels()
+	require.Error(t, err)
+	ensurePullRequestsHaveExpectedLabels(t, tests)
+
+	var prErrs PullRequestsError
+	require.ErrorAs(t, err, &prErrs)
+	require.Len(t, prErrs, 1)
+	assert.Equal(t, tests[0].GetNumber(), prErrs[0].Number)
 }
 
 func ensurePullRequestsHaveExpectedLabels(t *testing.T, tests []applyLabelsTest) {
@@ -154,6 +544,16 @@ func ensurePullRequestsHaveExpectedLabels(t *testing.T, tests []applyLabelsTest)
 	}
 }
 
+func ensurePullRequestsHaveOnlyExpectedLabels(t *testing.T, tests []applyLabelsTest) {
+	for _, test := range tests {
+		var names []string
+		for _, l := range test.Labels {
+			names = append(names, l.GetName())
+		}
+		assert.ElementsMatchf(t, test.expectedLabels, names, "PR#%d ('%s') labels", test.GetNumber(), test.GetTitle())
+	}
+}
+
 func prepareApplyLabelsLabeler(cases []applyLabelsTest) (*Labeler, *mockRepository) {
 	rs := prepareRepository()
 	ms := prepareMappings()
@@ -177,5 +577,9 @@ func convertPullRequest(pr pullRequest) (*github.PullRequest, []*github.CommitFi
 		name := name
 		cf = append(cf, &github.CommitFile{Filename: &name})
 	}
+	for _, name := range pr.labels {
+		name := name
+		pull.Labels = append(pull.Labels, &github.Label{Name: &name})
+	}
 	return pull, cf
 }
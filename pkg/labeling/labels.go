@@ -0,0 +1,76 @@
+package labeling
+
+import (
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	log "github.com/sirupsen/logrus"
+)
+
+// syncRepositoryLabels creates labels that are defined in the mappings, but missing in the repository, and updates
+// colors and descriptions of the existing ones. Only labels that define a color or a description are synced.
+func (l Labeler) syncRepositoryLabels() error {
+	defs := l.LabelDefinitions()
+	if len(defs) == 0 {
+		return nil
+	}
+
+	existing, err := l.Labels()
+	if err != nil {
+		return err
+	}
+	existingSet := make(map[string]*github.Label, len(existing))
+	for _, v := range existing {
+		// label names are case-insensitive
+		existingSet[strings.ToLower(v.GetName())] = v
+	}
+
+	for _, def := range defs {
+		label, ok := existingSet[strings.ToLower(def.GetName())]
+		switch {
+		case !ok:
+			if err := l.createLabel(def); err != nil {
+				return err
+			}
+		case !labelUpToDate(def, label):
+			if err := l.editLabel(def); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l Labeler) createLabel(def *github.Label) error {
+	entry := log.WithFields(log.Fields{"color": def.GetColor(), "description": def.GetDescription()})
+	entry.Debugf("label '%s' [dry run, creating]", def.GetName())
+	if l.DryRun {
+		return nil
+	}
+
+	entry.Infof("label '%s' [creating]", def.GetName())
+	return l.CreateLabel(def)
+}
+
+func (l Labeler) editLabel(def *github.Label) error {
+	entry := log.WithFields(log.Fields{"color": def.GetColor(), "description": def.GetDescription()})
+	entry.Debugf("label '%s' [dry run, updating]", def.GetName())
+	if l.DryRun {
+		return nil
+	}
+
+	entry.Infof("label '%s' [updating]", def.GetName())
+	return l.EditLabel(def)
+}
+
+// labelUpToDate reports whether the label has the color and the description of the definition.
+// Fields that the definition doesn't set are not compared.
+func labelUpToDate(def, label *github.Label) bool {
+	if def.Color != nil && !strings.EqualFold(def.GetColor(), label.GetColor()) {
+		return false
+	}
+	if def.Description != nil && def.GetDescription() != label.GetDescription() {
+		return false
+	}
+	return true
+}
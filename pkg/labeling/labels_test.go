@@ -0,0 +1,79 @@
+package labeling
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLabel(name, color, description string) *github.Label {
+	l := &github.Label{Name: github.String(name)}
+	if color != "" {
+		l.Color = github.String(color)
+	}
+	if description != "" {
+		l.Description = github.String(description)
+	}
+	return l
+}
+
+func TestLabeler_ApplyLabels_SyncsRepositoryLabels(t *testing.T) {
+	labeler, rs := prepareApplyLabelsLabeler(nil)
+	rs.labels = []*github.Label{
+		newLabel("collectors", "ededed", ""),
+		newLabel("Python.d", "3572a5", "python.d.plugin"),
+		newLabel("charts.d", "89e051", "outdated"),
+		newLabel("bug", "d73a4a", "Something isn't working"),
+	}
+	labeler.Mappings.(*mockMappings).definitions = []*github.Label{
+		newLabel("collectors", "0e8a16", "Data collection"),
+		newLabel("python.d", "3572A5", ""),
+		newLabel("charts.d", "", "charts.d.plugin"),
+		newLabel("charts.d/apache", "fbca04", ""),
+	}
+
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+
+	assert.Equal(t, []*github.Label{
+		newLabel("collectors", "0e8a16", "Data collection"),
+		newLabel("Python.d", "3572a5", "python.d.plugin"),
+		newLabel("charts.d", "89e051", "charts.d.plugin"),
+		newLabel("bug", "d73a4a", "Something isn't working"),
+		newLabel("charts.d/apache", "fbca04", ""),
+	}, rs.labels)
+}
+
+func TestLabeler_ApplyLabels_DoesntSyncRepositoryLabelsInDryRunMode(t *testing.T) {
+	labeler, rs := prepareApplyLabelsLabeler(nil)
+	labeler.DryRun = true
+	rs.labels = []*github.Label{newLabel("collectors", "ededed", "")}
+	labeler.Mappings.(*mockMappings).definitions = []*github.Label{
+		newLabel("collectors", "0e8a16", "Data collection"),
+		newLabel("python.d", "3572a5", ""),
+	}
+
+	_, err := labeler.ApplyLabels()
+	require.NoError(t, err)
+
+	assert.Equal(t, []*github.Label{newLabel("collectors", "ededed", "")}, rs.labels)
+}
+
+func TestLabeler_ApplyLabels_DoesntListRepositoryLabelsWithoutDefinitions(t *testing.T) {
+	labeler, rs := prepareApplyLabelsLabeler(nil)
+	rs.errOnLabels = true
+
+	_, err := labeler.ApplyLabels()
+	assert.NoError(t, err)
+}
+
+func TestLabeler_ApplyLabels_ReturnsErrorIfLabelsFails(t *testing.T) {
+	labeler, rs := prepareApplyLabelsLabeler(nil)
+	rs.errOnLabels = true
+	labeler.Mappings.(*mockMappings).definitions = []*github.Label{newLabel("collectors", "0e8a16", "")}
+
+	_, err := labeler.ApplyLabels()
+	assert.Error(t, err)
+}
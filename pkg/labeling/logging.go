@@ -0,0 +1,63 @@
+package labeling
+
+import (
+	"bytes"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// newBufferedLogger returns a logger that writes to a buffer instead of the standard logger output.
+// It allows to keep log lines of a pull request together when pull requests are processed concurrently.
+func newBufferedLogger() (*log.Logger, *bytes.Buffer) {
+	std := log.StandardLogger()
+	buf := &bytes.Buffer{}
+	logger := &log.Logger{
+		Out:       buf,
+		Formatter: std.Formatter,
+		Hooks:     std.Hooks,
+		Level:     std.GetLevel(),
+		ExitFunc:  std.ExitFunc,
+	}
+	return logger, buf
+}
+
+// orderedLogs writes buffered pull request logs to the standard logger output in pull requests order.
+type orderedLogs struct {
+	mu      sync.Mutex
+	next    int
+	pending []*bytes.Buffer
+}
+
+func newOrderedLogs(size int) *orderedLogs {
+	return &orderedLogs{pending: make([]*bytes.Buffer, size)}
+}
+
+// done stores the logs of the i-th pull request and writes all the logs that are ready to be written.
+func (o *orderedLogs) done(i int, buf *bytes.Buffer) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending[i] = buf
+	for o.next < len(o.pending) && o.pending[o.next] != nil {
+		o.write(o.next)
+		o.next++
+	}
+}
+
+// flush writes the remaining logs, skipping pull requests that weren't processed.
+func (o *orderedLogs) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for ; o.next < len(o.pending); o.next++ {
+		if o.pending[o.next] != nil {
+			o.write(o.next)
+		}
+	}
+}
+
+func (o *orderedLogs) write(i int) {
+	_, _ = o.pending[i].WriteTo(log.StandardLogger().Out)
+	o.pending[i] = nil
+}
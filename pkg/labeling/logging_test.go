@@ -0,0 +1,30 @@
+package labeling
+
+import (
+	"bytes"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderedLogs(t *testing.T) {
+	var out bytes.Buffer
+	std := log.StandardLogger()
+	defer std.SetOutput(std.Out)
+	std.SetOutput(&out)
+
+	logs := newOrderedLogs(4)
+
+	logs.done(1, bytes.NewBufferString("second\n"))
+	assert.Empty(t, out.String())
+
+	logs.done(0, bytes.NewBufferString("first\n"))
+	assert.Equal(t, "first\nsecond\n", out.String())
+
+	logs.done(3, bytes.NewBufferString("fourth\n"))
+	assert.Equal(t, "first\nsecond\n", out.String())
+
+	logs.flush()
+	assert.Equal(t, "first\nsecond\nfourth\n", out.String())
+}
@@ -4,15 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 )
 
 func prepareRepository() *mockRepository {
 	return &mockRepository{
-		owner:      "owner",
-		name:       "name",
-		pullsFiles: make(map[int][]*github.CommitFile),
+		owner:         "owner",
+		name:          "name",
+		pullsFiles:    make(map[int][]*github.CommitFile),
+		addLabelsErrs: make(map[int]error),
 	}
 }
 
@@ -22,6 +25,12 @@ type mockRepository struct {
 	errOnOpenPullRequests         bool
 	errOnPullRequestModifiedFiles bool
 	errOnAddLabelsToPullRequest   bool
+	errOnRemoveLabelFromPR        bool
+	truncatedFiles                bool
+	modifiedFilesCalls            atomic.Int32
+	addLabelsErrs                 map[int]error
+	errOnLabels                   bool
+	labels                        []*github.Label
 	pulls                         []*github.PullRequest
 	pullsFiles                    map[int][]*github.CommitFile
 }
@@ -34,34 +43,42 @@ func (r *mockRepository) Name() string {
 	return r.name
 }
 
-func (r *mockRepository) OpenPullRequests() ([]*github.PullRequest, error) {
+func (r *mockRepository) OpenPullRequests(since time.Time) ([]*github.PullRequest, error) {
 	if r.errOnOpenPullRequests {
 		return nil, errors.New("mock OpenPullRequests error")
 	}
 	var pulls []*github.PullRequest
 	for _, p := range r.pulls {
-		if *p.State == "open" {
+		if *p.State == "open" && !p.GetUpdatedAt().Before(since) {
 			pulls = append(pulls, p)
 		}
 	}
 	return pulls, nil
 }
 
-func (r *mockRepository) PullRequestModifiedFiles(number int) ([]*github.CommitFile, error) {
+func (r *mockRepository) PullRequest(number int) (*github.PullRequest, error) {
+	return r.findPullRequest(number)
+}
+
+func (r *mockRepository) PullRequestModifiedFiles(pull *github.PullRequest) ([]*github.CommitFile, bool, error) {
 	if r.errOnPullRequestModifiedFiles {
-		return nil, errors.New("mock PullRequestModifiedFiles error")
+		return nil, false, errors.New("mock PullRequestModifiedFiles error")
 	}
-	files, ok := r.pullsFiles[number]
+	r.modifiedFilesCalls.Add(1)
+	files, ok := r.pullsFiles[pull.GetNumber()]
 	if !ok {
-		return nil, fmt.Errorf("couldnt find PR#%d commit files", number)
+		return nil, false, fmt.Errorf("couldnt find PR#%d commit files", pull.GetNumber())
 	}
-	return files, nil
+	return files, r.truncatedFiles, nil
 }
 
 func (r *mockRepository) AddLabelsToPullRequest(prNum int, labels []string) error {
 	if r.errOnAddLabelsToPullRequest {
 		return errors.New("mock AddLabelsToPullRequest error")
 	}
+	if err := r.addLabelsErrs[prNum]; err != nil {
+		return err
+	}
 	pr, err := r.findPullRequest(prNum)
 	if err != nil {
 		return err
@@ -74,6 +91,55 @@ func (r *mockRepository) AddLabelsToPullRequest(prNum int, labels []string) erro
 	return nil
 }
 
+func (r *mockRepository) RemoveLabelFromPullRequest(prNum int, label string) error {
+	if r.errOnRemoveLabelFromPR {
+		return errors.New("mock RemoveLabelFromPullRequest error")
+	}
+	pr, err := r.findPullRequest(prNum)
+	if err != nil {
+		return err
+	}
+	for i, l := range pr.Labels {
+		if l.GetName() == label {
+			pr.Labels = append(pr.Labels[:i], pr.Labels[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("label '%s' not found on PR#%d", label, prNum)
+}
+
+func (r *mockRepository) Labels() ([]*github.Label, error) {
+	if r.errOnLabels {
+		return nil, errors.New("mock Labels error")
+	}
+	return r.labels, nil
+}
+
+func (r *mockRepository) CreateLabel(label *github.Label) error {
+	for _, l := range r.labels {
+		if strings.EqualFold(l.GetName(), label.GetName()) {
+			return fmt.Errorf("label '%s' already exists", label.GetName())
+		}
+	}
+	r.labels = append(r.labels, label)
+	return nil
+}
+
+func (r *mockRepository) EditLabel(label *github.Label) error {
+	for _, l := range r.labels {
+		if strings.EqualFold(l.GetName(), label.GetName()) {
+			if label.Color != nil {
+				l.Color = label.Color
+			}
+			if label.Description != nil {
+				l.Description = label.Description
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("label '%s' not found", label.GetName())
+}
+
 func (r *mockRepository) findPullRequest(num int) (*github.PullRequest, error) {
 	for _, p := range r.pulls {
 		if *p.Number == num {
@@ -91,12 +157,57 @@ func (r *mockRepository) addPullRequest(pull *github.PullRequest, files []*githu
 }
 
 func prepareMappings() *mockMappings {
-	return &mockMappings{}
+	return &mockMappings{removable: make(map[string]bool)}
 }
 
-type mockMappings struct{}
+type mockMappings struct {
+	removable          map[string]bool
+	definitions        []*github.Label
+	hash               string
+	errOnMatchedLabels bool
+}
+
+var mockManagedLabels = map[string]bool{
+	"collectors":      true,
+	"python.d":        true,
+	"python.d/apache": true,
+	"charts.d":        true,
+	"charts.d/apache": true,
+}
+
+func (m mockMappings) Managed(label string) bool {
+	return mockManagedLabels[label]
+}
+
+func (m mockMappings) Removable(label string) bool {
+	return m.removable[label]
+}
 
-func (mockMappings) MatchedLabels(files []*github.CommitFile) (labels []string) {
+func (m mockMappings) LabelDefinitions() []*github.Label {
+	return m.definitions
+}
+
+func (m mockMappings) MatchedFiles(label string, files []*github.CommitFile) []string {
+	var names []string
+	for _, f := range files {
+		labels, _ := m.MatchedLabels(nil, []*github.CommitFile{f})
+		for _, v := range labels {
+			if v == label {
+				names = append(names, f.GetFilename())
+			}
+		}
+	}
+	return names
+}
+
+func (m mockMappings) Hash() string {
+	return m.hash
+}
+
+func (m mockMappings) MatchedLabels(_ *github.PullRequest, files []*github.CommitFile) (labels []string, err error) {
+	if m.errOnMatchedLabels {
+		return nil, errors.New("mock MatchedLabels error")
+	}
 	set := make(map[string]bool)
 	for _, f := range files {
 		if strings.HasPrefix(*f.Filename, "collectors/") {
@@ -119,5 +230,22 @@ func (mockMappings) MatchedLabels(files []*github.CommitFile) (labels []string)
 		labels = append(labels, v)
 
 	}
-	return labels
+	return labels, nil
+}
+
+type mockState map[string]string
+
+func (m mockState) Unchanged(repo string, number int, headSHA, pullHash, mappingsHash string) bool {
+	v, ok := m[fmt.Sprintf("%s#%d", repo, number)]
+	return ok && v == headSHA+"/"+pullHash+"/"+mappingsHash
+}
+
+func (m mockState) Record(repo string, number int, headSHA, pullHash, mappingsHash string) {
+	m[fmt.Sprintf("%s#%d", repo, number)] = headSHA + "/" + pullHash + "/" + mappingsHash
+}
+
+type mockReporter []PullRequestReport
+
+func (m *mockReporter) Report(r PullRequestReport) {
+	*m = append(*m, r)
 }
@@ -0,0 +1,44 @@
+package labeling
+
+// Reporter receives the labeling result of each pull request.
+type Reporter interface {
+	Report(r PullRequestReport)
+}
+
+// PullRequestReport is the labeling result of a single pull request. In dry run mode Added and Removed are the labels
+// that would be added and removed.
+type PullRequestReport struct {
+	Repository string `json:"repository"`
+	Number     int    `json:"number"`
+	// Labels are the matched labels.
+	Labels []string `json:"labels,omitempty"`
+	// Files are the matched files per label.
+	Files   map[string][]string `json:"files,omitempty"`
+	Added   []string            `json:"added,omitempty"`
+	Removed []string            `json:"removed,omitempty"`
+	// Skipped is the reason the pull request was skipped.
+	Skipped string `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (l Labeler) newReport(number int) PullRequestReport {
+	return PullRequestReport{Repository: l.repoSlug(), Number: number}
+}
+
+func (l Labeler) reportResults(res []pullResult) {
+	for _, r := range res {
+		if !r.processed || l.Reporter == nil {
+			continue
+		}
+		if r.err != nil {
+			r.report.Error = r.err.Error()
+		}
+		l.report(r.report)
+	}
+}
+
+func (l Labeler) report(r PullRequestReport) {
+	if l.Reporter != nil {
+		l.Reporter.Report(r)
+	}
+}
@@ -0,0 +1,183 @@
+package mappings
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+type (
+	// target is a pull request label conditions are evaluated against.
+	target struct {
+		pull  *github.PullRequest
+		files []*github.CommitFile
+		teams Teams
+	}
+	// condition is a single label condition, all label conditions must match to apply the label.
+	condition interface {
+		eval(t *target) (bool, error)
+	}
+)
+
+// fileCondition returns whether a changed file matches any of the file conditions (patterns, 'all-files', 'patch')
+// of the condition, nested ones included.
+func fileCondition(c condition, file *github.CommitFile) bool {
+	switch c := c.(type) {
+	case patterns:
+		return c.match(file)
+	case allFilesCondition:
+		return c.match(file)
+	case *patchCondition:
+		return c.matchFile(file)
+	case anyCondition:
+		return anyFileCondition(c, file)
+	case allCondition:
+		return anyFileCondition(c, file)
+	}
+	return false
+}
+
+func anyFileCondition(conditions []condition, file *github.CommitFile) bool {
+	for _, c := range conditions {
+		if fileCondition(c, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// eval matches if any of the changed files matches the patterns.
+func (ps patterns) eval(t *target) (bool, error) {
+	for _, file := range t.files {
+		if ps.match(file) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// allFilesCondition matches if every changed file matches the patterns.
+type allFilesCondition struct {
+	patterns
+}
+
+func (c allFilesCondition) eval(t *target) (bool, error) {
+	for _, file := range t.files {
+		if !c.match(file) {
+			return false, nil
+		}
+	}
+	return len(t.files) > 0, nil
+}
+
+// anyCondition matches if any of the conditions matches.
+type anyCondition []condition
+
+func (c anyCondition) eval(t *target) (bool, error) {
+	for _, v := range c {
+		if ok, err := v.eval(t); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// allCondition matches if all the conditions match.
+type allCondition []condition
+
+func (c allCondition) eval(t *target) (bool, error) {
+	for _, v := range c {
+		if ok, err := v.eval(t); err != nil || !ok {
+			return false, err
+		}
+	}
+	return len(c) > 0, nil
+}
+
+type (
+	pullField int
+	// fieldCondition matches if a pull request field (branch name, title, body, author) matches any of the matchers.
+	fieldCondition struct {
+		field pullField
+		textMatchers
+	}
+)
+
+const (
+	headBranchField pullField = iota
+	baseBranchField
+	titleField
+	bodyField
+	authorField
+)
+
+func (c fieldCondition) eval(t *target) (bool, error) {
+	switch c.field {
+	case headBranchField:
+		return c.match(t.pull.GetHead().GetRef()), nil
+	case baseBranchField:
+		return c.match(t.pull.GetBase().GetRef()), nil
+	case titleField:
+		return c.match(t.pull.GetTitle()), nil
+	case bodyField:
+		return c.match(t.pull.GetBody()), nil
+	case authorField:
+		return c.match(t.pull.GetUser().GetLogin()), nil
+	}
+	return false, nil
+}
+
+// forkCondition matches if the pull request comes (or doesn't come) from a fork. It doesn't match if neither
+// repository is known, f.e. for a list of local paths.
+type forkCondition bool
+
+func (c forkCondition) eval(t *target) (bool, error) {
+	head := t.pull.GetHead().GetRepo()
+	if head == nil && t.pull.GetBase().GetRepo() == nil {
+		return false, nil
+	}
+	// head repository is nil if the fork was deleted
+	fork := head == nil || head.GetFullName() != t.pull.GetBase().GetRepo().GetFullName()
+	return fork == bool(c), nil
+}
+
+type (
+	team struct {
+		org  string
+		slug string
+	}
+	// teamCondition matches if the pull request author is a member of any of the teams.
+	teamCondition []team
+)
+
+func (c teamCondition) eval(t *target) (bool, error) {
+	login := t.pull.GetUser().GetLogin()
+	// a pull request without an author, f.e. a list of local paths, is not a team member
+	if login == "" {
+		return false, nil
+	}
+	if t.teams == nil {
+		return false, errors.New("team membership lookup is not configured")
+	}
+	for _, tm := range c {
+		ok, err := t.teams.IsTeamMember(tm.org, tm.slug, login)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func newTeams(values []string) (teamCondition, error) {
+	var c teamCondition
+	for _, value := range values {
+		parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(value), "@"), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("bad team '%s', expected 'org/team'", value)
+		}
+		c = append(c, team{org: parts[0], slug: parts[1]})
+	}
+	return c, nil
+}
@@ -0,0 +1,82 @@
+package mappings
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v45/github"
+)
+
+type (
+	// Evidence explains a label match: the files and the patterns that matched the label, and the files that
+	// negated patterns excluded.
+	Evidence struct {
+		Label    string
+		Matched  bool
+		Matches  []FileMatch
+		Excluded []FileMatch
+	}
+	// FileMatch is a changed file and the pattern that matched it.
+	FileMatch struct {
+		File    string
+		Pattern string
+	}
+)
+
+// MatchedLabelsEvidence is MatchedLabels that returns match evidence for all the labels, the matched ones and
+// the ones that didn't match.
+func (ms Mappings) MatchedLabelsEvidence(pull *github.PullRequest, files []*github.CommitFile) ([]Evidence, error) {
+	t := &target{pull: pull, files: files, teams: ms.Teams}
+	var evs []Evidence
+	for _, l := range ms.labels {
+		ok, err := l.match(t)
+		if err != nil {
+			return nil, fmt.Errorf("matching label '%s': %v", l.name, err)
+		}
+		ev := Evidence{Label: l.name, Matched: ok}
+		for _, file := range files {
+			for _, c := range l.conditions {
+				ev.collect(c, file)
+			}
+		}
+		evs = append(evs, ev)
+	}
+	if ms.size != nil {
+		if name := ms.size.match(files); name != "" {
+			evs = append(evs, Evidence{Label: name, Matched: true})
+		}
+	}
+	return evs, nil
+}
+
+// collect records the decisions of the file conditions for the file.
+func (ev *Evidence) collect(c condition, file *github.CommitFile) {
+	switch c := c.(type) {
+	case patterns:
+		ev.collectPatterns(c, file)
+	case allFilesCondition:
+		ev.collectPatterns(c.patterns, file)
+	case *patchCondition:
+		if c.matchFile(file) {
+			ev.Matches = append(ev.Matches, FileMatch{File: file.GetFilename(), Pattern: "patch"})
+		}
+	case anyCondition:
+		for _, v := range c {
+			ev.collect(v, file)
+		}
+	case allCondition:
+		for _, v := range c {
+			ev.collect(v, file)
+		}
+	}
+}
+
+func (ev *Evidence) collectPatterns(ps patterns, file *github.CommitFile) {
+	p := ps.decide(file)
+	switch {
+	case p == nil:
+	case p.positive:
+		ev.Matches = append(ev.Matches, FileMatch{File: file.GetFilename(), Pattern: p.String()})
+	default:
+		ev.Excluded = append(ev.Excluded, FileMatch{File: file.GetFilename(), Pattern: p.String()})
+	}
+}
@@ -0,0 +1,233 @@
+package mappings
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a mistake in the mappings that Parse accepts.
+type Problem struct {
+	Label string
+	// Pattern is the pattern as written in the mappings, empty for label problems.
+	Pattern string
+	Message string
+	// Warning is set for the problems that don't change what the mappings match, f.e. extra whitespace.
+	Warning bool
+}
+
+func (p Problem) String() string {
+	if p.Pattern == "" {
+		return fmt.Sprintf("label '%s': %s", p.Label, p.Message)
+	}
+	return fmt.Sprintf("label '%s': pattern '%s': %s", p.Label, p.Pattern, p.Message)
+}
+
+// Lint checks the mappings for duplicate labels and patterns, labels whose patterns are a subset of another label
+// patterns, negations that can never apply, '!' and whitespace mistakes, and, if tree (the repository file paths)
+// is not nil, patterns that match no file in the repository.
+func (ms Mappings) Lint(tree []string) []Problem {
+	problems := lintTags(ms.conf)
+	seen := make(map[string]bool)
+	for _, l := range ms.labels {
+		if seen[l.name] {
+			problems = append(problems, Problem{Label: l.name, Message: "duplicate label"})
+		}
+		seen[l.name] = true
+		for _, ps := range collectPatterns(l.conditions) {
+			problems = append(problems, lintPatterns(l.name, ps, tree)...)
+		}
+	}
+	if ms.size != nil {
+		problems = append(problems, lintPatterns(sizeKey, ms.size.exclude, tree)...)
+	}
+	return append(problems, ms.lintSubsets()...)
+}
+
+// collectPatterns returns the pattern lists of the conditions, nested ones included.
+func collectPatterns(conditions []condition) []patterns {
+	var lists []patterns
+	for _, c := range conditions {
+		switch c := c.(type) {
+		case patterns:
+			lists = append(lists, c)
+		case allFilesCondition:
+			lists = append(lists, c.patterns)
+		case *patchCondition:
+			if len(c.files) > 0 {
+				lists = append(lists, c.files)
+			}
+		case anyCondition:
+			lists = append(lists, collectPatterns(c)...)
+		case allCondition:
+			lists = append(lists, collectPatterns(c)...)
+		}
+	}
+	return lists
+}
+
+// lintTags reports the mappings file scalars with a YAML tag: an unquoted "- !docs/*" is the "!docs/*" tag on an
+// empty value, the pattern is silently dropped.
+func lintTags(conf []byte) []Problem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(conf, &doc); err != nil || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	var problems []Problem
+	top := doc.Content[0].Content
+	for i := 0; i+1 < len(top); i += 2 {
+		label := top[i].Value
+		walkTagged(top[i+1], func(n *yaml.Node) {
+			problems = append(problems, Problem{
+				Label:   label,
+				Pattern: strings.TrimSpace(n.Tag + " " + n.Value),
+				Message: fmt.Sprintf("line %d: unquoted '!' is a YAML tag, quote the pattern", n.Line),
+			})
+		})
+	}
+	return problems
+}
+
+// walkTagged calls fn for the scalars with a local tag ("!tag", not a standard "!!str" one).
+func walkTagged(n *yaml.Node, fn func(*yaml.Node)) {
+	if n.Kind == yaml.ScalarNode && strings.HasPrefix(n.Tag, "!") && !strings.HasPrefix(n.Tag, "!!") {
+		fn(n)
+	}
+	for _, c := range n.Content {
+		walkTagged(c, fn)
+	}
+}
+
+func lintPatterns(label string, ps patterns, tree []string) []Problem {
+	var problems []Problem
+	add := func(p *pattern, format string, args ...interface{}) {
+		problems = append(problems, Problem{Label: label, Pattern: p.source, Message: fmt.Sprintf(format, args...)})
+	}
+	warn := func(p *pattern, message string) {
+		problems = append(problems, Problem{Label: label, Pattern: p.source, Message: message, Warning: true})
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range ps {
+		switch {
+		case p.source != strings.TrimSpace(p.source):
+			warn(p, "leading or trailing whitespace")
+		case !p.positive && len(p.source) > 1 && unicode.IsSpace(rune(p.source[1])):
+			warn(p, "whitespace after '!'")
+		}
+		switch {
+		case p.raw == "" || p.positive && p.raw == "!":
+			add(p, "'!' without a pattern")
+			continue
+		case p.positive && strings.HasPrefix(p.raw, "!"):
+			add(p, "'!' after whitespace is not a negation")
+		case strings.HasPrefix(p.raw, "!"):
+			add(p, "double '!'")
+		}
+
+		if seen[p.String()] {
+			add(p, "duplicate pattern")
+			continue
+		}
+		seen[p.String()] = true
+
+		if !p.positive && !overlapsPositive(p, ps) {
+			add(p, "negation never applies, no pattern of the list overlaps it")
+		}
+		if tree != nil && p.status != "added" && p.status != "removed" && !matchesAny(p, tree) {
+			add(p, "matches no file in the repository")
+		}
+	}
+	return problems
+}
+
+// overlapsPositive reports whether any positive pattern of the list can match a file the negated pattern matches.
+// Two patterns can match the same file only if the literal prefix of one is a prefix of the other one.
+func overlapsPositive(neg *pattern, ps patterns) bool {
+	for _, p := range ps {
+		if !p.positive || (p.status != "" && neg.status != "" && p.status != neg.status) {
+			continue
+		}
+		a, b := literalPrefix(neg.raw), literalPrefix(p.raw)
+		if strings.HasPrefix(a, b) || strings.HasPrefix(b, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// literalPrefix returns the part of the glob pattern before the first special character.
+func literalPrefix(raw string) string {
+	if i := strings.IndexAny(raw, "*?[{\\"); i >= 0 {
+		return raw[:i]
+	}
+	return raw
+}
+
+func matchesAny(p *pattern, tree []string) bool {
+	for _, path := range tree {
+		if p.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintSubsets reports the labels that have only patterns and always match together with another such label:
+// their positive patterns are a subset of the other label positive patterns, and the other label negations are
+// a subset of theirs.
+func (ms Mappings) lintSubsets() []Problem {
+	type patternSets struct {
+		positive, negative map[string]bool
+	}
+	sets := make(map[string]patternSets)
+	var names []string
+	for _, l := range ms.labels {
+		if len(l.conditions) != 1 {
+			continue
+		}
+		ps, ok := l.conditions[0].(patterns)
+		if !ok {
+			continue
+		}
+		s := patternSets{positive: make(map[string]bool), negative: make(map[string]bool)}
+		for _, p := range ps {
+			if p.positive {
+				s.positive[p.String()] = true
+			} else {
+				s.negative[p.String()] = true
+			}
+		}
+		if _, ok := sets[l.name]; !ok {
+			names = append(names, l.name)
+		}
+		sets[l.name] = s
+	}
+
+	var problems []Problem
+	for _, a := range names {
+		for _, b := range names {
+			if a == b || len(sets[a].positive) == 0 {
+				continue
+			}
+			if isSubset(sets[a].positive, sets[b].positive) && isSubset(sets[b].negative, sets[a].negative) {
+				problems = append(problems, Problem{
+					Label:   a,
+					Message: fmt.Sprintf("patterns are a subset of label '%s' patterns, it is always applied together with it", b),
+				})
+			}
+		}
+	}
+	return problems
+}
+
+func isSubset(a, b map[string]bool) bool {
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package mappings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappings_Lint(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		tree  []string
+		want  []Problem
+	}{
+		"no problems": {
+			input: "collectors:\n  - collectors/**/*\n  - '!collectors/python.d.plugin/**/*'\ndocs:\n  - docs/*\n",
+			tree:  []string{"collectors/apps.plugin/apps.c", "collectors/python.d.plugin/apache/apache.chart.py", "docs/README.md"},
+		},
+		"duplicate label": {
+			input: "docs:\n  - docs/*\ndocs:\n  - web/*\n",
+			want:  []Problem{{Label: "docs", Message: "duplicate label"}},
+		},
+		"duplicate pattern": {
+			input: "docs:\n  - docs/*\n  - docs/*\n",
+			want:  []Problem{{Label: "docs", Pattern: "docs/*", Message: "duplicate pattern"}},
+		},
+		"impossible negation": {
+			input: "docs:\n  - docs/*\n  - '!collectors/*'\n",
+			want: []Problem{{
+				Label:   "docs",
+				Pattern: "!collectors/*",
+				Message: "negation never applies, no pattern of the list overlaps it",
+			}},
+		},
+		"negation in nested block": {
+			input: "docs:\n  any:\n    - [docs/*, '!src/*']\n",
+			want: []Problem{{
+				Label:   "docs",
+				Pattern: "!src/*",
+				Message: "negation never applies, no pattern of the list overlaps it",
+			}},
+		},
+		"whitespace after negation": {
+			input: "collectors:\n  - collectors/**/*\n  - '! collectors/apps.plugin/*'\n",
+			want: []Problem{{
+				Label:   "collectors",
+				Pattern: "! collectors/apps.plugin/*",
+				Message: "whitespace after '!'",
+				Warning: true,
+			}},
+		},
+		"negation after whitespace": {
+			input: "collectors:\n  - collectors/**/*\n  - ' !collectors/apps.plugin/*'\n",
+			want: []Problem{
+				{Label: "collectors", Pattern: " !collectors/apps.plugin/*", Message: "leading or trailing whitespace", Warning: true},
+				{Label: "collectors", Pattern: " !collectors/apps.plugin/*", Message: "'!' after whitespace is not a negation"},
+			},
+		},
+		"bare negation": {
+			input: "docs:\n  - docs/*\n  - '!'\n",
+			want:  []Problem{{Label: "docs", Pattern: "!", Message: "'!' without a pattern"}},
+		},
+		"unquoted negation": {
+			input: "docs:\n  - docs/**/*\n  - !docs/internal/*\n",
+			want: []Problem{{
+				Label:   "docs",
+				Pattern: "!docs/internal/*",
+				Message: "line 3: unquoted '!' is a YAML tag, quote the pattern",
+			}},
+		},
+		"double negation": {
+			input: "collectors:\n  - collectors/**/*\n  - '!!collectors/apps.plugin/*'\n",
+			want: []Problem{
+				{Label: "collectors", Pattern: "!!collectors/apps.plugin/*", Message: "double '!'"},
+				{
+					Label:   "collectors",
+					Pattern: "!!collectors/apps.plugin/*",
+					Message: "negation never applies, no pattern of the list overlaps it",
+				},
+			},
+		},
+		"dead pattern": {
+			input: "docs:\n  - docs/*\n  - added:guides/*\n  - manuals/*\n",
+			tree:  []string{"docs/README.md"},
+			want:  []Problem{{Label: "docs", Pattern: "manuals/*", Message: "matches no file in the repository"}},
+		},
+		"dead size exclude": {
+			input: "size:\n  exclude: [vendor/**/*]\n  labels:\n    size/S: 0\n",
+			tree:  []string{"docs/README.md"},
+			want:  []Problem{{Label: "size", Pattern: "vendor/**/*", Message: "matches no file in the repository"}},
+		},
+		"subset label": {
+			input: "collectors:\n  - collectors/**/*\n  - web/*\napps.plugin:\n  - collectors/**/*\n",
+			want: []Problem{{
+				Label:   "apps.plugin",
+				Message: "patterns are a subset of label 'collectors' patterns, it is always applied together with it",
+			}},
+		},
+		"subset with negation": {
+			input: "collectors:\n  - collectors/**/*\n  - '!collectors/apps.plugin/*'\napps.plugin:\n  - collectors/**/*\n",
+			want: []Problem{{
+				Label:   "collectors",
+				Message: "patterns are a subset of label 'apps.plugin' patterns, it is always applied together with it",
+			}},
+		},
+		"not a subset": {
+			input: "collectors:\n  - collectors/**/*\n  - web/*\napps.plugin:\n  - collectors/**/*\n  - apps/*\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ms, err := Parse([]byte(test.input))
+			require.NoError(t, err)
+
+			assert.Equal(t, test.want, ms.Lint(test.tree))
+		})
+	}
+}
+
+func TestMappings_Lint_testdata(t *testing.T) {
+	ms, err := FromFile("testdata/labeler.yaml")
+	require.NoError(t, err)
+
+	assert.Equal(t, []Problem{
+		{
+			Label:   "collectors",
+			Pattern: "!collectors/cgroups.plugin/*",
+			Message: "line 27: unquoted '!' is a YAML tag, quote the pattern",
+		},
+		{Label: "collectors", Pattern: "! collectors/apps.plugin/*", Message: "whitespace after '!'", Warning: true},
+	}, ms.Lint(nil))
+}
+
+func TestProblem_String(t *testing.T) {
+	assert.Equal(t, "label 'docs': duplicate label", Problem{Label: "docs", Message: "duplicate label"}.String())
+	assert.Equal(t,
+		"label 'docs': pattern 'docs/*': duplicate pattern",
+		Problem{Label: "docs", Pattern: "docs/*", Message: "duplicate pattern"}.String(),
+	)
+}
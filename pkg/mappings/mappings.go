@@ -1,6 +1,7 @@
 package mappings
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/google/go-github/v45/github"
@@ -8,11 +9,21 @@ import (
 
 type (
 	label struct {
-		name string
-		patterns
+		name        string
+		remove      bool
+		color       string
+		description string
+		conditions  []condition
 	}
 	Mappings struct {
+		// Teams resolves team membership for 'author-team' conditions.
+		Teams  Teams
 		labels []*label
+		size   *sizeLabels
+		tests  []TestCase
+		hash   string
+		// conf is the mappings file, Lint checks it for the YAML mistakes that decoding hides.
+		conf []byte
 	}
 )
 
@@ -20,6 +31,10 @@ type Repository interface {
 	FileContent(filePath string) (*github.RepositoryContent, error)
 }
 
+type Teams interface {
+	IsTeamMember(org, team, user string) (bool, error)
+}
+
 func FromFile(filepath string) (*Mappings, error) {
 	b, err := os.ReadFile(filepath)
 	if err != nil {
@@ -37,15 +52,98 @@ func FromGitHub(filepath string, r Repository) (*Mappings, error) {
 	return Parse([]byte(c))
 }
 
-func (ms Mappings) MatchedLabels(files []*github.CommitFile) (labels []string) {
-	set := make(map[string]bool)
-	for _, file := range files {
-		for _, l := range ms.labels {
-			if !set[l.name] && l.match(file.GetFilename()) {
-				set[l.name] = true
-				labels = append(labels, l.name)
+// MatchedLabels returns the labels that match the pull request and its changed files, in the order they are defined
+// in the mappings, followed by the size label.
+func (ms Mappings) MatchedLabels(pull *github.PullRequest, files []*github.CommitFile) (labels []string, err error) {
+	t := &target{pull: pull, files: files, teams: ms.Teams}
+	for _, l := range ms.labels {
+		ok, err := l.match(t)
+		if err != nil {
+			return nil, fmt.Errorf("matching label '%s': %v", l.name, err)
+		}
+		if ok {
+			labels = append(labels, l.name)
+		}
+	}
+	if ms.size != nil {
+		if name := ms.size.match(files); name != "" {
+			labels = append(labels, name)
+		}
+	}
+	return labels, nil
+}
+
+func (l label) match(t *target) (bool, error) {
+	return allCondition(l.conditions).eval(t)
+}
+
+// MatchedFiles returns the names of the changed files that match the file conditions of the label. Size labels match
+// the counted files.
+func (ms Mappings) MatchedFiles(name string, files []*github.CommitFile) []string {
+	var names []string
+	if l := ms.lookup(name); l != nil {
+		for _, file := range files {
+			if anyFileCondition(l.conditions, file) {
+				names = append(names, file.GetFilename())
+			}
+		}
+	} else if ms.size.lookup(name) {
+		for _, file := range files {
+			if !ms.size.exclude.match(file) {
+				names = append(names, file.GetFilename())
 			}
 		}
 	}
-	return labels
+	return names
+}
+
+// Managed reports whether the label is defined in the mappings.
+func (ms Mappings) Managed(name string) bool {
+	return ms.lookup(name) != nil || ms.size.lookup(name)
+}
+
+// Removable reports whether the label is configured to be removed from pull requests it no longer matches.
+// Size labels are always removable, a pull request has only one of them.
+func (ms Mappings) Removable(name string) bool {
+	l := ms.lookup(name)
+	return l != nil && l.remove || ms.size.lookup(name)
+}
+
+// LabelDefinitions returns the labels that define a color or a description.
+func (ms Mappings) LabelDefinitions() []*github.Label {
+	var defs []*github.Label
+	for _, l := range ms.labels {
+		if l.color == "" && l.description == "" {
+			continue
+		}
+		def := &github.Label{Name: github.String(l.name)}
+		if l.color != "" {
+			def.Color = github.String(l.color)
+		}
+		if l.description != "" {
+			def.Description = github.String(l.description)
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// WithoutSizeLabels returns a copy of the mappings without the size labels, for changed files without line counts.
+func (ms Mappings) WithoutSizeLabels() *Mappings {
+	ms.size = nil
+	return &ms
+}
+
+// Hash is the SHA-256 hash of the mappings file, it changes whenever the mappings do.
+func (ms Mappings) Hash() string {
+	return ms.hash
+}
+
+func (ms Mappings) lookup(name string) *label {
+	for _, l := range ms.labels {
+		if l.name == name {
+			return l
+		}
+	}
+	return nil
 }
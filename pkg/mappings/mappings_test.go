@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/go-github/v45/github"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFromFile(t *testing.T) {
@@ -84,6 +85,14 @@ func TestMappings_MatchedLabels(t *testing.T) {
 			input:      []string{".github/stale.yml", "build/m4/tmalloc.m4", "collectors/python.d.plugin/example/example.chart.py"},
 			wantLabels: []string{"github", "build", "collectors"},
 		},
+		{
+			input:      []string{"collectors/python.d.plugin/example/example.chart.py", "build/m4/tmalloc.m4", ".github/stale.yml"},
+			wantLabels: []string{"github", "build", "collectors"},
+		},
+		{
+			input:      []string{"docs/guides/install.md"},
+			wantLabels: []string{"docs"},
+		},
 		{
 			input: []string{"build/build.sh"},
 		},
@@ -103,11 +112,524 @@ func TestMappings_MatchedLabels(t *testing.T) {
 	for i, test := range tests {
 		t.Run(fmt.Sprintf("test case #%d (%v)", i+1, test.input), func(t *testing.T) {
 			files := prepareGithubCommitFiles(test.input)
-			assert.Equal(t, test.wantLabels, ms.MatchedLabels(files))
+			labels, err := ms.MatchedLabels(nil, files)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantLabels, labels)
+		})
+	}
+}
+
+func TestMappings_MatchedLabels_Branches(t *testing.T) {
+	conf := []byte(`
+release:
+  base-branch: release/*
+fix:
+  head-branch:
+    - /^(fix|hotfix)-\d+$/
+    - bugfix/*
+docs-release:
+  patterns: docs/*
+  base-branch: release/*
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	tests := []struct {
+		head, base string
+		files      []string
+		wantLabels []string
+	}{
+		{head: "feature/x", base: "release/1.0", wantLabels: []string{"release"}},
+		{head: "fix-123", base: "master", wantLabels: []string{"fix"}},
+		{head: "hotfix-1", base: "release/2.0", wantLabels: []string{"release", "fix"}},
+		{head: "bugfix/crash", base: "master", files: []string{"docs/README.md"}, wantLabels: []string{"fix"}},
+		{head: "feature/x", base: "release/1.0", files: []string{"docs/README.md"}, wantLabels: []string{"release", "docs-release"}},
+		{head: "fix-abc", base: "release/1.0/rc", files: []string{"docs/README.md"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("test case #%d (%s -> %s)", i+1, test.head, test.base), func(t *testing.T) {
+			pull := &github.PullRequest{
+				Head: &github.PullRequestBranch{Ref: github.String(test.head)},
+				Base: &github.PullRequestBranch{Ref: github.String(test.base)},
+			}
+			files := prepareGithubCommitFiles(test.files)
+			labels, err := ms.MatchedLabels(pull, files)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantLabels, labels)
 		})
 	}
 }
 
+func TestMappings_MatchedLabels_TitleAndBody(t *testing.T) {
+	conf := []byte(`
+type/fix:
+  title: '^fix(\(.+\))?!?:'
+type/feat:
+  title: /^feat(\(.+\))?!?:/
+breaking:
+  title: '^\w+(\(.+\))?!:'
+  body: BREAKING CHANGE
+collectors:
+  - collectors/**/*
+collectors/fix:
+  patterns: collectors/**/*
+  title: ^fix
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	tests := []struct {
+		title, body string
+		files       []string
+		wantLabels  []string
+	}{
+		{title: "fix: crash on start", wantLabels: []string{"type/fix"}},
+		{title: "fix(apps.plugin): crash", files: []string{"collectors/apps.plugin/apps.c"}, wantLabels: []string{"type/fix", "collectors", "collectors/fix"}},
+		{title: "feat(api)!: new endpoint", body: "BREAKING CHANGE: old endpoint removed", wantLabels: []string{"type/feat", "breaking"}},
+		{title: "feat!: new endpoint", wantLabels: []string{"type/feat"}},
+		{title: "docs: fix typo", files: []string{"collectors/apps.plugin/README.md"}, wantLabels: []string{"collectors"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("test case #%d (%s)", i+1, test.title), func(t *testing.T) {
+			pull := &github.PullRequest{Title: github.String(test.title), Body: github.String(test.body)}
+			files := prepareGithubCommitFiles(test.files)
+			labels, err := ms.MatchedLabels(pull, files)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantLabels, labels)
+		})
+	}
+}
+
+func TestMappings_MatchedLabels_AuthorAndFork(t *testing.T) {
+	conf := []byte(`
+external-contribution:
+  from-fork: true
+team/infra:
+  author-team: "@netdata/infra"
+bots:
+  author:
+    - dependabot\[bot\]
+    - /-bot$/
+infra/collectors:
+  patterns: collectors/**/*
+  author-team:
+    - netdata/infra
+    - netdata/agent
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+	teams := &mockTeams{members: map[string][]string{
+		"netdata/infra": {"ilyam8", "ktsaou"},
+		"netdata/agent": {"thiagoftsm"},
+	}}
+	ms.Teams = teams
+
+	tests := []struct {
+		author     string
+		headRepo   string
+		files      []string
+		wantLabels []string
+	}{
+		{author: "ilyam8", headRepo: "netdata/netdata", wantLabels: []string{"team/infra"}},
+		{author: "contributor", headRepo: "contributor/netdata", wantLabels: []string{"external-contribution"}},
+		{author: "contributor", wantLabels: []string{"external-contribution"}},
+		{author: "dependabot[bot]", headRepo: "netdata/netdata", wantLabels: []string{"bots"}},
+		{author: "release-bot", headRepo: "netdata/netdata", wantLabels: []string{"bots"}},
+		{author: "thiagoftsm", headRepo: "netdata/netdata", files: []string{"collectors/apps.plugin/apps.c"}, wantLabels: []string{"infra/collectors"}},
+		{author: "ktsaou", headRepo: "ktsaou/netdata", files: []string{"collectors/apps.plugin/apps.c"}, wantLabels: []string{"external-contribution", "team/infra", "infra/collectors"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("test case #%d (%s)", i+1, test.author), func(t *testing.T) {
+			pull := &github.PullRequest{
+				User: &github.User{Login: github.String(test.author)},
+				Head: &github.PullRequestBranch{},
+				Base: &github.PullRequestBranch{Repo: &github.Repository{FullName: github.String("netdata/netdata")}},
+			}
+			if test.headRepo != "" {
+				pull.Head.Repo = &github.Repository{FullName: github.String(test.headRepo)}
+			}
+			files := prepareGithubCommitFiles(test.files)
+
+			labels, err := ms.MatchedLabels(pull, files)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantLabels, labels)
+		})
+	}
+}
+
+func TestMappings_MatchedLabels_ForkUnknown(t *testing.T) {
+	ms, err := Parse([]byte("fork:\n  from-fork: true\ninternal:\n  from-fork: false\n"))
+	require.NoError(t, err)
+
+	// neither repository is known for a list of local paths
+	labels, err := ms.MatchedLabels(&github.PullRequest{}, prepareGithubCommitFiles([]string{"main.go"}))
+
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+}
+
+func TestMappings_MatchedLabels_AnyAllBlocks(t *testing.T) {
+	conf := []byte(`
+docs-only:
+  all-files:
+    - docs/**/*
+    - "*.md"
+api-migration:
+  all:
+    - src/api/**/*
+    - migrations/**/*
+area/api:
+  any:
+    - src/api/**/*
+    - title: ^api
+    - all:
+        - proto/**/*
+        - head-branch: api-*
+collectors:
+  - collectors/**/*
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	tests := []struct {
+		title, branch string
+		files         []string
+		wantLabels    []string
+	}{
+		{files: []string{"docs/guides/install.md", "README.md"}, wantLabels: []string{"docs-only"}},
+		{files: []string{"docs/guides/install.md", "src/main.go"}},
+		{files: []string{"src/api/v1/server.go", "migrations/sql/0001.sql"}, wantLabels: []string{"api-migration", "area/api"}},
+		{files: []string{"src/api/v1/server.go"}, wantLabels: []string{"area/api"}},
+		{title: "api: new endpoint", wantLabels: []string{"area/api"}},
+		{branch: "api-v2", files: []string{"proto/api/v2/api.proto"}, wantLabels: []string{"area/api"}},
+		{branch: "main", files: []string{"proto/api/v2/api.proto"}},
+		{files: []string{"collectors/apps.plugin/apps.c"}, wantLabels: []string{"collectors"}},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("test case #%d", i+1), func(t *testing.T) {
+			pull := &github.PullRequest{
+				Title: github.String(test.title),
+				Head:  &github.PullRequestBranch{Ref: github.String(test.branch)},
+			}
+			files := prepareGithubCommitFiles(test.files)
+
+			labels, err := ms.MatchedLabels(pull, files)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantLabels, labels)
+		})
+	}
+}
+
+func TestMappings_MatchedLabels_FileStatus(t *testing.T) {
+	conf := []byte(`
+new-collector:
+  - added:collectors/**/*
+collectors:
+  - collectors/**/*
+removed-docs:
+  - removed:docs/**/*
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	file := func(status, name, previous string) *github.CommitFile {
+		f := &github.CommitFile{Status: github.String(status), Filename: github.String(name)}
+		if previous != "" {
+			f.PreviousFilename = github.String(previous)
+		}
+		return f
+	}
+
+	tests := map[string]struct {
+		file       *github.CommitFile
+		wantLabels []string
+	}{
+		"added collector":           {file: file("added", "collectors/new.plugin/new.c", ""), wantLabels: []string{"new-collector", "collectors"}},
+		"modified collector":        {file: file("modified", "collectors/apps.plugin/apps.c", ""), wantLabels: []string{"collectors"}},
+		"removed docs":              {file: file("removed", "docs/guides/install.md", ""), wantLabels: []string{"removed-docs"}},
+		"renamed out of collectors": {file: file("renamed", "libnetdata/apps/apps.c", "collectors/apps.plugin/apps.c"), wantLabels: []string{"collectors"}},
+		"renamed into collectors":   {file: file("renamed", "collectors/apps.plugin/apps.c", "libnetdata/apps/apps.c"), wantLabels: []string{"collectors"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			labels, err := ms.MatchedLabels(&github.PullRequest{}, []*github.CommitFile{test.file})
+			require.NoError(t, err)
+			assert.Equal(t, test.wantLabels, labels)
+		})
+	}
+}
+
+func TestMappings_MatchedLabels_Patch(t *testing.T) {
+	conf := []byte(`
+breaking:
+  patch:
+    files: "**/*.go"
+    removed: '^func [A-Z]'
+needs-migration:
+  patch: ALTER TABLE
+todo:
+  patch:
+    added: /TODO/
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	file := func(name, patch string) *github.CommitFile {
+		return &github.CommitFile{Filename: github.String(name), Patch: github.String(patch)}
+	}
+
+	tests := map[string]struct {
+		file       *github.CommitFile
+		wantLabels []string
+	}{
+		"removed exported function": {
+			file:       file("pkg/api/api.go", "@@ -1,3 +1,2 @@\n package api\n-func Serve() {}\n+func serve() {}"),
+			wantLabels: []string{"breaking"},
+		},
+		"added exported function": {
+			file: file("pkg/api/api.go", "@@ -1,2 +1,3 @@\n package api\n+func Serve() {}"),
+		},
+		"removed exported function outside of files": {
+			file: file("docs/api.md", "@@ -1,2 +1,1 @@\n-func Serve() {}"),
+		},
+		"alter table on either side": {
+			file:       file("migrations/0002.sql", "@@ -1,1 +1,1 @@\n-ALTER TABLE users ADD age int;\n+ALTER TABLE users ADD age bigint;"),
+			wantLabels: []string{"needs-migration"},
+		},
+		"alter table in context line": {
+			file: file("migrations/0002.sql", "@@ -1,2 +1,3 @@\n ALTER TABLE users ADD age int;\n+-- comment"),
+		},
+		"added todo": {
+			file:       file("main.c", "@@ -1,1 +1,2 @@\n int main() {}\n+// TODO: args"),
+			wantLabels: []string{"todo"},
+		},
+		"no patch": {
+			file: &github.CommitFile{Filename: github.String("logo.png")},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			labels, err := ms.MatchedLabels(&github.PullRequest{}, []*github.CommitFile{test.file})
+			require.NoError(t, err)
+			assert.Equal(t, test.wantLabels, labels)
+		})
+	}
+}
+
+func TestMappings_MatchedLabels_Size(t *testing.T) {
+	conf := []byte(`
+docs:
+  - docs/**/*
+size:
+  exclude:
+    - "**/package-lock.json"
+    - "*.lock"
+  labels:
+    size/XL: 500
+    size/XS: 0
+    size/S: 10
+    size/M: 30
+    size/L: 100
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	file := func(name string, changes int) *github.CommitFile {
+		return &github.CommitFile{Filename: github.String(name), Changes: github.Int(changes)}
+	}
+
+	tests := map[string]struct {
+		files      []*github.CommitFile
+		wantLabels []string
+	}{
+		"no changes":        {wantLabels: []string{"size/XS"}},
+		"extra small":       {files: []*github.CommitFile{file("main.go", 9)}, wantLabels: []string{"size/XS"}},
+		"small threshold":   {files: []*github.CommitFile{file("main.go", 4), file("main_test.go", 6)}, wantLabels: []string{"size/S"}},
+		"large":             {files: []*github.CommitFile{file("docs/guides/install.md", 120)}, wantLabels: []string{"docs", "size/L"}},
+		"extra large":       {files: []*github.CommitFile{file("main.go", 1000)}, wantLabels: []string{"size/XL"}},
+		"excluded lockfile": {files: []*github.CommitFile{file("main.go", 20), file("web/package-lock.json", 5000), file("go.lock", 300)}, wantLabels: []string{"size/S"}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			labels, err := ms.MatchedLabels(&github.PullRequest{}, test.files)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantLabels, labels)
+		})
+	}
+
+	assert.True(t, ms.Managed("size/M"))
+	assert.True(t, ms.Removable("size/M"))
+	assert.False(t, ms.Removable("docs"))
+
+	labels, err := ms.WithoutSizeLabels().MatchedLabels(&github.PullRequest{}, []*github.CommitFile{file("docs/guides/install.md", 0)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs"}, labels)
+	assert.False(t, ms.WithoutSizeLabels().Managed("size/M"))
+	assert.True(t, ms.Managed("size/M"), "the mappings are not modified")
+}
+
+func TestMappings_MatchedLabels_ReturnsErrorIfTeamsLookupFails(t *testing.T) {
+	ms, err := Parse([]byte("team/infra:\n  author-team: netdata/infra\n"))
+	require.NoError(t, err)
+	pull := &github.PullRequest{User: &github.User{Login: github.String("ilyam8")}}
+
+	_, err = ms.MatchedLabels(pull, nil)
+	assert.Error(t, err)
+
+	ms.Teams = &mockTeams{err: errors.New("mock IsTeamMember error")}
+	_, err = ms.MatchedLabels(pull, nil)
+	assert.Error(t, err)
+
+	ms.Teams = nil
+	labels, err := ms.MatchedLabels(&github.PullRequest{}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, labels)
+}
+
+func TestMappings_MatchedFiles(t *testing.T) {
+	conf := []byte(`
+collectors:
+  - collectors/**/*
+  - "!collectors/README.md"
+go-api:
+  any:
+    - title: ^api
+    - patch:
+        files: "**/*.go"
+        added: ^func
+type/fix:
+  title: ^fix
+size:
+  exclude: "*.lock"
+  labels:
+    size/S: 0
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+	files := prepareGithubCommitFiles([]string{"collectors/apps.plugin/apps.c", "collectors/README.md", "pkg/api/api.go", "go.lock"})
+	files[2].Patch = github.String("@@ -1,1 +1,2 @@\n package api\n+func Serve() {}")
+
+	assert.Equal(t, []string{"collectors/apps.plugin/apps.c"}, ms.MatchedFiles("collectors", files))
+	assert.Equal(t, []string{"pkg/api/api.go"}, ms.MatchedFiles("go-api", files))
+	assert.Empty(t, ms.MatchedFiles("type/fix", files))
+	assert.Equal(t, []string{"collectors/apps.plugin/apps.c", "collectors/README.md", "pkg/api/api.go"}, ms.MatchedFiles("size/S", files))
+	assert.Empty(t, ms.MatchedFiles("bug", files))
+}
+
+func TestMappings_MatchedLabelsEvidence(t *testing.T) {
+	conf := []byte(`
+collectors:
+  - collectors/**/*
+  - "!collectors/README.md"
+new-collector:
+  - added:collectors/**/*
+area/packaging:
+  any:
+    - packaging/**/*
+    - all-files: "*.spec"
+docs:
+  - "!docs/archive/*"
+  - docs/**/*
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+	files := prepareGithubCommitFiles([]string{"collectors/apps.plugin/apps.c", "collectors/README.md", "packaging/docker/Dockerfile", "docs/archive/old.md"})
+
+	evs, err := ms.MatchedLabelsEvidence(&github.PullRequest{}, files)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Evidence{
+		{
+			Label:    "collectors",
+			Matched:  true,
+			Matches:  []FileMatch{{File: "collectors/apps.plugin/apps.c", Pattern: "collectors/**/*"}},
+			Excluded: []FileMatch{{File: "collectors/README.md", Pattern: "!collectors/README.md"}},
+		},
+		{Label: "new-collector"},
+		{
+			Label:   "area/packaging",
+			Matched: true,
+			Matches: []FileMatch{{File: "packaging/docker/Dockerfile", Pattern: "packaging/**/*"}},
+		},
+		{
+			Label:    "docs",
+			Excluded: []FileMatch{{File: "docs/archive/old.md", Pattern: "!docs/archive/*"}},
+		},
+	}, evs)
+
+	labels, err := ms.MatchedLabels(&github.PullRequest{}, files)
+	require.NoError(t, err)
+	var matched []string
+	for _, ev := range evs {
+		if ev.Matched {
+			matched = append(matched, ev.Label)
+		}
+	}
+	assert.Equal(t, labels, matched)
+}
+
+func TestMappings_Managed(t *testing.T) {
+	ms := prepareValidConfigurationMappings(t)
+
+	assert.True(t, ms.Managed("github"))
+	assert.True(t, ms.Managed("docs"))
+	assert.False(t, ms.Managed("bug"))
+}
+
+func TestMappings_Removable(t *testing.T) {
+	ms := prepareValidConfigurationMappings(t)
+
+	assert.True(t, ms.Removable("docs"))
+	assert.False(t, ms.Removable("github"))
+	assert.False(t, ms.Removable("bug"))
+}
+
+func TestMappings_LabelDefinitions(t *testing.T) {
+	ms := prepareValidConfigurationMappings(t)
+
+	assert.Equal(t, []*github.Label{
+		{
+			Name:        github.String("docs"),
+			Color:       github.String("0075ca"),
+			Description: github.String("Improvements or additions to documentation"),
+		},
+	}, ms.LabelDefinitions())
+}
+
+func TestMappings_Hash(t *testing.T) {
+	ms := prepareValidConfigurationMappings(t)
+	other, err := Parse(append(append([]byte{}, validConfig...), "\nbug: bug/*\n"...))
+	require.NoError(t, err)
+
+	assert.Len(t, ms.Hash(), 64)
+	assert.Equal(t, ms.Hash(), prepareValidConfigurationMappings(t).Hash())
+	assert.NotEqual(t, ms.Hash(), other.Hash())
+}
+
+type mockTeams struct {
+	members map[string][]string
+	err     error
+}
+
+func (m mockTeams) IsTeamMember(org, team, user string) (bool, error) {
+	if m.err != nil {
+		return false, m.err
+	}
+	for _, v := range m.members[org+"/"+team] {
+		if v == user {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 type mockRepository struct{}
 
 func (r mockRepository) FileContent(filePath string) (*github.RepositoryContent, error) {
@@ -0,0 +1,84 @@
+package mappings
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+type (
+	// textMatcher matches a string against a glob pattern or, if the value is enclosed in slashes ("/^fix-.*/"),
+	// against a regular expression.
+	textMatcher struct {
+		raw string
+		re  *regexp.Regexp
+		glob.Glob
+	}
+	textMatchers []*textMatcher
+)
+
+func (ms textMatchers) match(s string) bool {
+	for _, m := range ms {
+		if m.match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m textMatcher) match(s string) bool {
+	if m.re != nil {
+		return m.re.MatchString(s)
+	}
+	return m.Match(s)
+}
+
+// newTextMatchers creates matchers, if regexpOnly is set the values are regular expressions (not globs).
+func newTextMatchers(values []string, regexpOnly bool) (textMatchers, error) {
+	newMatcher := newTextMatcher
+	if regexpOnly {
+		newMatcher = newRegexpMatcher
+	}
+	var ms textMatchers
+	for _, value := range values {
+		m, err := newMatcher(value)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+func newTextMatcher(value string) (*textMatcher, error) {
+	value = strings.TrimSpace(value)
+	if isRegexp(value) {
+		return newRegexpMatcher(value)
+	}
+
+	g, err := glob.Compile(value, '/')
+	if err != nil {
+		return nil, err
+	}
+	return &textMatcher{raw: value, Glob: g}, nil
+}
+
+// newRegexpMatcher returns a matcher that always treats the value as a regular expression,
+// enclosing slashes are optional.
+func newRegexpMatcher(value string) (*textMatcher, error) {
+	expr := value
+	if isRegexp(value) {
+		expr = value[1 : len(value)-1]
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("bad regular expression '%s': %v", value, err)
+	}
+	return &textMatcher{raw: value, re: re}, nil
+}
+
+func isRegexp(value string) bool {
+	return len(value) > 1 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/")
+}
@@ -1,16 +1,21 @@
 package mappings
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
+// Parse parses label mappings. Labels keep the order of the document.
 func Parse(conf []byte) (*Mappings, error) {
-	var userMappings map[string]interface{}
+	var userMappings yaml.MapSlice
 	if err := yaml.Unmarshal(conf, &userMappings); err != nil {
 		return nil, fmt.Errorf("label mappings unmarshaling: %v", err)
 	}
@@ -18,32 +23,350 @@ func Parse(conf []byte) (*Mappings, error) {
 		return nil, errors.New("empty label mappings")
 	}
 
-	var mappings Mappings
-	for name, value := range userMappings {
-		l, err := parseLabel(name, value)
+	sum := sha256.Sum256(conf)
+	mappings := Mappings{hash: hex.EncodeToString(sum[:]), conf: conf}
+	for _, item := range userMappings {
+		name := fmt.Sprint(item.Key)
+		if name == sizeKey && isSizeSection(item.Value) {
+			size, err := parseSize(item.Value)
+			if err != nil {
+				return nil, fmt.Errorf("mapping size labels: %v", err)
+			}
+			mappings.size = size
+			continue
+		}
+		if name == testsKey && isTestsSection(item.Value) {
+			tests, err := parseTests(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			mappings.tests = tests
+			continue
+		}
+		l, err := parseLabel(name, item.Value)
 		if err != nil {
 			return nil, err
 		}
 		mappings.labels = append(mappings.labels, l)
 	}
+	if mappings.size != nil {
+		for _, l := range mappings.size.labels {
+			if mappings.lookup(l.name) != nil {
+				return nil, fmt.Errorf("size label '%s' is also a mapping label", l.name)
+			}
+		}
+	}
 	return &mappings, nil
 }
 
+type (
+	labelConfig struct {
+		conditionsConfig `yaml:",inline"`
+		Remove           bool   `yaml:"remove"`
+		Color            string `yaml:"color"`
+		Description      string `yaml:"description"`
+	}
+	conditionsConfig struct {
+		Patterns   interface{}   `yaml:"patterns"`
+		AllFiles   interface{}   `yaml:"all-files"`
+		HeadBranch interface{}   `yaml:"head-branch"`
+		BaseBranch interface{}   `yaml:"base-branch"`
+		Title      interface{}   `yaml:"title"`
+		Body       interface{}   `yaml:"body"`
+		Author     interface{}   `yaml:"author"`
+		AuthorTeam interface{}   `yaml:"author-team"`
+		FromFork   *bool         `yaml:"from-fork"`
+		Patch      interface{}   `yaml:"patch"`
+		Any        []interface{} `yaml:"any"`
+		All        []interface{} `yaml:"all"`
+	}
+	patchConfig struct {
+		Files   interface{} `yaml:"files"`
+		Added   interface{} `yaml:"added"`
+		Removed interface{} `yaml:"removed"`
+		Lines   interface{} `yaml:"lines"`
+	}
+)
+
+var reColor = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
 func parseLabel(name string, value interface{}) (*label, error) {
-	values, err := mappingToSlice(value)
+	var conf labelConfig
+	if isMapping(value) {
+		if err := decodeMapping(value, &conf); err != nil {
+			return nil, fmt.Errorf("mapping label '%s': %v", name, err)
+		}
+	} else {
+		conf.Patterns = value
+	}
+
+	color := strings.TrimPrefix(conf.Color, "#")
+	if color != "" && !reColor.MatchString(color) {
+		return nil, fmt.Errorf("mapping label '%s': bad color '%s', expected 6 hex digits", name, conf.Color)
+	}
+
+	conditions, err := parseConditions(conf.conditionsConfig)
 	if err != nil {
 		return nil, fmt.Errorf("mapping label '%s': %v", name, err)
 	}
-	if values = removeEmpty(values); len(values) == 0 {
-		return nil, fmt.Errorf("mapping label '%s' has no pattern(s)", name)
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("mapping label '%s' has no pattern(s) or condition(s)", name)
+	}
+
+	l := label{
+		name:        name,
+		remove:      conf.Remove,
+		color:       strings.ToLower(color),
+		description: conf.Description,
+		conditions:  conditions,
+	}
+	return &l, nil
+}
+
+// parseConditions parses conditions that all must match.
+func parseConditions(conf conditionsConfig) ([]condition, error) {
+	var conditions []condition
+
+	if conf.Patterns != nil {
+		ps, err := parsePatterns(conf.Patterns)
+		if err != nil {
+			return nil, err
+		}
+		if len(ps) > 0 {
+			conditions = append(conditions, ps)
+		}
+	}
+
+	if conf.AllFiles != nil {
+		ps, err := parsePatterns(conf.AllFiles)
+		if err != nil {
+			return nil, err
+		}
+		if len(ps) > 0 {
+			conditions = append(conditions, allFilesCondition{patterns: ps})
+		}
+	}
+
+	for _, v := range []struct {
+		field      pullField
+		value      interface{}
+		regexpOnly bool
+	}{
+		{field: headBranchField, value: conf.HeadBranch},
+		{field: baseBranchField, value: conf.BaseBranch},
+		{field: titleField, value: conf.Title, regexpOnly: true},
+		{field: bodyField, value: conf.Body, regexpOnly: true},
+		{field: authorField, value: conf.Author},
+	} {
+		if v.value == nil {
+			continue
+		}
+		ms, err := parseTextMatchers(v.value, v.regexpOnly)
+		if err != nil {
+			return nil, err
+		}
+		if len(ms) > 0 {
+			conditions = append(conditions, fieldCondition{field: v.field, textMatchers: ms})
+		}
+	}
+
+	if conf.AuthorTeam != nil {
+		values, err := mappingToSlice(conf.AuthorTeam)
+		if err != nil {
+			return nil, err
+		}
+		teams, err := newTeams(removeEmpty(values))
+		if err != nil {
+			return nil, err
+		}
+		if len(teams) > 0 {
+			conditions = append(conditions, teams)
+		}
+	}
+
+	if conf.FromFork != nil {
+		conditions = append(conditions, forkCondition(*conf.FromFork))
+	}
+
+	if conf.Patch != nil {
+		c, err := parsePatch(conf.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("'patch': %v", err)
+		}
+		conditions = append(conditions, c)
+	}
+
+	for _, v := range []struct {
+		name   string
+		blocks []interface{}
+		all    bool
+	}{
+		{name: "any", blocks: conf.Any},
+		{name: "all", blocks: conf.All, all: true},
+	} {
+		if len(v.blocks) == 0 {
+			continue
+		}
+		var group []condition
+		for _, block := range v.blocks {
+			c, err := parseBlock(block)
+			if err != nil {
+				return nil, fmt.Errorf("'%s': %v", v.name, err)
+			}
+			group = append(group, c)
+		}
+		if v.all {
+			conditions = append(conditions, allCondition(group))
+		} else {
+			conditions = append(conditions, anyCondition(group))
+		}
+	}
+
+	return conditions, nil
+}
+
+// parseBlock parses an 'any'/'all' block. A block is either a list of patterns or a set of conditions
+// that all must match.
+func parseBlock(value interface{}) (condition, error) {
+	var conf conditionsConfig
+	if isMapping(value) {
+		if err := decodeMapping(value, &conf); err != nil {
+			return nil, err
+		}
+	} else {
+		conf.Patterns = value
+	}
+
+	conditions, err := parseConditions(conf)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(conditions) == 0:
+		return nil, errors.New("block has no pattern(s) or condition(s)")
+	case len(conditions) == 1:
+		return conditions[0], nil
+	}
+	return allCondition(conditions), nil
+}
+
+// parsePatch parses a 'patch' condition. A list of regular expressions is a shorthand for 'lines'.
+func parsePatch(value interface{}) (*patchCondition, error) {
+	var conf patchConfig
+	if isMapping(value) {
+		if err := decodeMapping(value, &conf); err != nil {
+			return nil, err
+		}
+	} else {
+		conf.Lines = value
 	}
 
-	ps, err := newPatterns(values)
+	var c patchCondition
+	if conf.Files != nil {
+		ps, err := parsePatterns(conf.Files)
+		if err != nil {
+			return nil, err
+		}
+		c.files = ps
+	}
+	for _, v := range []struct {
+		value interface{}
+		ms    *textMatchers
+	}{
+		{value: conf.Added, ms: &c.added},
+		{value: conf.Removed, ms: &c.removed},
+		{value: conf.Lines, ms: &c.lines},
+	} {
+		if v.value == nil {
+			continue
+		}
+		ms, err := parseTextMatchers(v.value, true)
+		if err != nil {
+			return nil, err
+		}
+		*v.ms = ms
+	}
+	if len(c.added) == 0 && len(c.removed) == 0 && len(c.lines) == 0 {
+		return nil, errors.New("no 'added', 'removed' or 'lines' regular expression(s)")
+	}
+	return &c, nil
+}
+
+func parsePatterns(value interface{}) (patterns, error) {
+	values, err := mappingToSlice(value)
 	if err != nil {
-		return nil, fmt.Errorf("mapping label '%s': %v", name, err)
+		return nil, err
+	}
+	ps, err := newPatterns(removeEmpty(values))
+	if err != nil {
+		return nil, err
 	}
 	placeNegativeFirst(ps)
-	return &label{name: name, patterns: ps}, nil
+	return ps, nil
+}
+
+func parseTextMatchers(value interface{}, regexpOnly bool) (textMatchers, error) {
+	values, err := mappingToSlice(value)
+	if err != nil {
+		return nil, err
+	}
+	return newTextMatchers(removeEmpty(values), regexpOnly)
+}
+
+// isSizeSection reports whether the 'size' key is the size labels section rather than a label named 'size':
+// a mapping with 'labels' or 'exclude' option.
+func isSizeSection(value interface{}) bool {
+	for _, k := range mappingKeys(value) {
+		if k == "labels" || k == "exclude" {
+			return true
+		}
+	}
+	return false
+}
+
+// isTestsSection reports whether the 'tests' key is the test cases section rather than a label named 'tests':
+// a list of mappings.
+func isTestsSection(value interface{}) bool {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if !isMapping(item) {
+			return false
+		}
+	}
+	return true
+}
+
+func mappingKeys(value interface{}) []string {
+	var keys []string
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		for _, item := range v {
+			keys = append(keys, fmt.Sprint(item.Key))
+		}
+	case map[interface{}]interface{}:
+		for k := range v {
+			keys = append(keys, fmt.Sprint(k))
+		}
+	}
+	return keys
+}
+
+func isMapping(value interface{}) bool {
+	switch value.(type) {
+	case yaml.MapSlice, map[interface{}]interface{}:
+		return true
+	}
+	return false
+}
+
+func decodeMapping(value interface{}, conf interface{}) error {
+	bs, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalStrict(bs, conf)
 }
 
 func mappingToSlice(mapping interface{}) ([]string, error) {
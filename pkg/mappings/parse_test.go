@@ -2,7 +2,6 @@ package mappings
 
 import (
 	"os"
-	"sort"
 	"testing"
 
 	"github.com/gobwas/glob"
@@ -29,22 +28,59 @@ func TestParse(t *testing.T) {
 		wantErr    bool
 	}{
 		"valid configuration": {input: validConfig, wantLabels: []*label{
-			{name: "build", patterns: patterns{
-				{positive: true, raw: "build/**/*", Glob: globMust("build/**/*")},
-			}},
-			{name: "collectors", patterns: patterns{
-				{positive: false, raw: "collectors/apps.plugin/*", Glob: globMust("collectors/apps.plugin/*")},
-				{positive: false, raw: "collectors/README.md", Glob: globMust("collectors/README.md")},
-				{positive: true, raw: "collectors/*", Glob: globMust("collectors/*")},
-				{positive: true, raw: "collectors/**/*", Glob: globMust("collectors/**/*")},
-			}},
-			{name: "github", patterns: patterns{
-				{positive: true, raw: ".github/*", Glob: globMust(".github/*")},
-				{positive: true, raw: ".github/**/*", Glob: globMust(".github/**/*")},
-			}},
+			{name: "github", conditions: []condition{patterns{
+				{positive: true, raw: ".github/*", source: ".github/*", Glob: globMust(".github/*")},
+				{positive: true, raw: ".github/**/*", source: ".github/**/*", Glob: globMust(".github/**/*")},
+			}}},
+			{name: "build", conditions: []condition{patterns{
+				{positive: true, raw: "build/**/*", source: "build/**/*", Glob: globMust("build/**/*")},
+			}}},
+			{name: "docs", remove: true, color: "0075ca", description: "Improvements or additions to documentation", conditions: []condition{patterns{
+				{positive: true, raw: "docs/**/*", source: "docs/**/*", Glob: globMust("docs/**/*")},
+			}}},
+			{name: "collectors", conditions: []condition{patterns{
+				{positive: false, raw: "collectors/apps.plugin/*", source: "! collectors/apps.plugin/*", Glob: globMust("collectors/apps.plugin/*")},
+				{positive: false, raw: "collectors/README.md", source: "!collectors/README.md", Glob: globMust("collectors/README.md")},
+				{positive: true, raw: "collectors/*", source: "collectors/*", Glob: globMust("collectors/*")},
+				{positive: true, raw: "collectors/**/*", source: "collectors/**/*", Glob: globMust("collectors/**/*")},
+			}}},
 		}},
-		"invalid configuration": {input: invalidConfig, wantErr: true},
-		"empty configuration":   {input: emptyConfig, wantErr: true},
+		"status qualifier": {input: []byte("new:\n  - '!added:src/*_test.go'\n  - added:src/*\n"), wantLabels: []*label{
+			{name: "new", conditions: []condition{patterns{
+				{positive: false, status: "added", raw: "src/*_test.go", source: "!added:src/*_test.go", Glob: globMust("src/*_test.go")},
+				{positive: true, status: "added", raw: "src/*", source: "added:src/*", Glob: globMust("src/*")},
+			}}},
+		}},
+		"tests label": {input: []byte("tests:\n  - '**/*_test.go'\n"), wantLabels: []*label{
+			{name: "tests", conditions: []condition{patterns{
+				{positive: true, raw: "**/*_test.go", source: "**/*_test.go", Glob: globMust("**/*_test.go")},
+			}}},
+		}},
+		"size label": {input: []byte("size:\n  patterns: size/*\n"), wantLabels: []*label{
+			{name: "size", conditions: []condition{patterns{
+				{positive: true, raw: "size/*", source: "size/*", Glob: globMust("size/*")},
+			}}},
+		}},
+		"invalid configuration":          {input: invalidConfig, wantErr: true},
+		"empty configuration":            {input: emptyConfig, wantErr: true},
+		"label options without patterns": {input: []byte("docs:\n  remove: true\n"), wantErr: true},
+		"unknown label option":           {input: []byte("docs:\n  patterns: docs/*\n  colour: 0075ca\n"), wantErr: true},
+		"bad label color":                {input: []byte("docs:\n  patterns: docs/*\n  color: blue\n"), wantErr: true},
+		"bad branch regexp":              {input: []byte("fix:\n  head-branch: /^fix-(/\n"), wantErr: true},
+		"bad branch glob":                {input: []byte("fix:\n  head-branch: fix-[\n"), wantErr: true},
+		"bad title regexp":               {input: []byte("fix:\n  title: ^fix(\n"), wantErr: true},
+		"bad author team":                {input: []byte("infra:\n  author-team: infra\n"), wantErr: true},
+		"bad from fork":                  {input: []byte("external:\n  from-fork: maybe\n"), wantErr: true},
+		"empty any block":                {input: []byte("api:\n  any:\n    - {}\n"), wantErr: true},
+		"unknown block option":           {input: []byte("api:\n  all:\n    - remove: true\n"), wantErr: true},
+		"patch without regexp":           {input: []byte("breaking:\n  patch:\n    files: '*.go'\n"), wantErr: true},
+		"bad patch regexp":               {input: []byte("breaking:\n  patch:\n    removed: ^func (\n"), wantErr: true},
+		"size without labels":            {input: []byte("size:\n  exclude: '*.lock'\n"), wantErr: true},
+		"bad size threshold":             {input: []byte("size:\n  labels:\n    size/S: ten\n"), wantErr: true},
+		"duplicate size threshold":       {input: []byte("size:\n  labels:\n    size/S: 10\n    size/M: 10\n"), wantErr: true},
+		"size label is mapping label":    {input: []byte("size/S: src/*\nsize:\n  labels:\n    size/S: 10\n"), wantErr: true},
+		"bad tests section":              {input: []byte("docs: docs/*\ntests:\n  - files: [docs/a.md]\n    expected: [docs]\n"), wantErr: true},
+		"bad nested block pattern":       {input: []byte("api:\n  any:\n    - all:\n        - title: ^api(\n"), wantErr: true},
 	}
 
 	for name, test := range tests {
@@ -55,7 +91,6 @@ func TestParse(t *testing.T) {
 				require.NotNil(t, ms)
 				require.NoError(t, err)
 
-				sort.Slice(ms.labels, func(i, j int) bool { return ms.labels[i].name < ms.labels[j].name })
 				assert.Equal(t, test.wantLabels, ms.labels)
 			} else {
 				assert.Nil(t, ms)
@@ -65,6 +100,19 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParse_KeepsLabelsOrder(t *testing.T) {
+	conf := []byte("z: z/*\na: a/*\nm:\n  patterns: m/*\n10: 10/*\n")
+
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	var names []string
+	for _, l := range ms.labels {
+		names = append(names, l.name)
+	}
+	assert.Equal(t, []string{"z", "a", "m", "10"}, names)
+}
+
 func globMust(pattern string) glob.Glob {
 	return glob.MustCompile(pattern, '/')
 }
@@ -0,0 +1,57 @@
+package mappings
+
+import (
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// patchCondition matches if a changed line of the files patches matches any of the regular expressions.
+type patchCondition struct {
+	// files limits the condition to the files matching the patterns, all files if empty.
+	files patterns
+	// added, removed and lines match added, removed and either side lines respectively.
+	added   textMatchers
+	removed textMatchers
+	lines   textMatchers
+}
+
+func (c patchCondition) eval(t *target) (bool, error) {
+	for _, file := range t.files {
+		if c.matchFile(file) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (c patchCondition) matchFile(file *github.CommitFile) bool {
+	if len(c.files) > 0 && !c.files.match(file) {
+		return false
+	}
+	return c.matchPatch(file.GetPatch())
+}
+
+// matchPatch matches the lines of a unified diff patch. GitHub omits the patch of binary and very large files,
+// they never match.
+func (c patchCondition) matchPatch(patch string) bool {
+	for _, line := range strings.Split(patch, "\n") {
+		if line == "" {
+			continue
+		}
+		var side textMatchers
+		switch line[0] {
+		case '+':
+			side = c.added
+		case '-':
+			side = c.removed
+		default:
+			continue
+		}
+		line = strings.TrimSuffix(line[1:], "\r")
+		if side.match(line) || c.lines.match(line) {
+			return true
+		}
+	}
+	return false
+}
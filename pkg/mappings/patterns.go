@@ -4,24 +4,61 @@ import (
 	"strings"
 
 	"github.com/gobwas/glob"
+	"github.com/google/go-github/v45/github"
 )
 
 type (
 	pattern struct {
 		positive bool
-		raw      string
+		// status limits the pattern to the files with the status, any status if empty.
+		status string
+		raw    string
+		// source is the pattern as written in the mappings.
+		source string
 		glob.Glob
 	}
 	patterns []*pattern
 )
 
-func (ps patterns) match(name string) bool {
+// fileStatuses are the pull request file statuses a pattern can be limited to, f.e. "added:collectors/**/*".
+var fileStatuses = []string{"added", "removed", "modified", "renamed", "copied", "changed", "unchanged"}
+
+func (ps patterns) match(file *github.CommitFile) bool {
+	p := ps.decide(file)
+	return p != nil && p.positive
+}
+
+// decide returns the first pattern that matches the file, negated patterns come first. It returns nil if none does.
+func (ps patterns) decide(file *github.CommitFile) *pattern {
 	for _, p := range ps {
-		if p.Match(name) {
-			return p.positive
+		if p.matchFile(file) {
+			return p
 		}
 	}
-	return false
+	return nil
+}
+
+// matchFile matches the file name, or the previous file name if the file is renamed.
+func (p pattern) matchFile(file *github.CommitFile) bool {
+	if p.status != "" && p.status != file.GetStatus() {
+		return false
+	}
+	if p.Match(file.GetFilename()) {
+		return true
+	}
+	return file.GetPreviousFilename() != "" && p.Match(file.GetPreviousFilename())
+}
+
+// String returns the pattern as written in the mappings, with the negation and the status qualifier.
+func (p pattern) String() string {
+	s := p.raw
+	if p.status != "" {
+		s = p.status + ":" + s
+	}
+	if !p.positive {
+		s = "!" + s
+	}
+	return s
 }
 
 func newPatterns(values []string) (patterns, error) {
@@ -37,11 +74,13 @@ func newPatterns(values []string) (patterns, error) {
 }
 
 func newPattern(value string) (*pattern, error) {
+	source := value
 	positive := !(value[0] == '!' && len(value) > 1)
 	if !positive {
 		value = value[1:]
 	}
 	value = strings.TrimSpace(value)
+	status, value := splitStatus(value)
 
 	g, err := glob.Compile(value, '/')
 	if err != nil {
@@ -50,8 +89,20 @@ func newPattern(value string) (*pattern, error) {
 
 	p := pattern{
 		positive: positive,
+		status:   status,
 		raw:      value,
+		source:   source,
 		Glob:     g,
 	}
 	return &p, nil
 }
+
+// splitStatus splits the status qualifier off the pattern. Only known statuses are qualifiers.
+func splitStatus(value string) (status, rest string) {
+	for _, s := range fileStatuses {
+		if strings.HasPrefix(value, s+":") {
+			return s, value[len(s)+1:]
+		}
+	}
+	return "", value
+}
@@ -0,0 +1,99 @@
+package mappings
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-github/v45/github"
+	"gopkg.in/yaml.v2"
+)
+
+// sizeKey is the mappings file key of the size labels section.
+const sizeKey = "size"
+
+type (
+	sizeLabel struct {
+		name string
+		// lines is the minimum number of changed lines.
+		lines int
+	}
+	// sizeLabels are ordered by the number of lines.
+	sizeLabels struct {
+		labels  []sizeLabel
+		exclude patterns
+	}
+	sizeConfig struct {
+		Exclude interface{}   `yaml:"exclude"`
+		Labels  yaml.MapSlice `yaml:"labels"`
+	}
+)
+
+// match returns the size label of the changed files, or an empty string if the changes are smaller than
+// the smallest threshold.
+func (s sizeLabels) match(files []*github.CommitFile) string {
+	var lines int
+	for _, file := range files {
+		if !s.exclude.match(file) {
+			lines += file.GetChanges()
+		}
+	}
+	var name string
+	for _, l := range s.labels {
+		if lines < l.lines {
+			break
+		}
+		name = l.name
+	}
+	return name
+}
+
+func (s *sizeLabels) lookup(name string) bool {
+	if s == nil {
+		return false
+	}
+	for _, l := range s.labels {
+		if l.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSize(value interface{}) (*sizeLabels, error) {
+	var conf sizeConfig
+	if !isMapping(value) {
+		return nil, errors.New("expected 'labels' and 'exclude' options")
+	}
+	if err := decodeMapping(value, &conf); err != nil {
+		return nil, err
+	}
+	if len(conf.Labels) == 0 {
+		return nil, errors.New("no size labels")
+	}
+
+	var s sizeLabels
+	if conf.Exclude != nil {
+		ps, err := parsePatterns(conf.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		s.exclude = ps
+	}
+
+	seen := make(map[int]string)
+	for _, item := range conf.Labels {
+		name := fmt.Sprint(item.Key)
+		lines, ok := item.Value.(int)
+		if !ok || lines < 0 {
+			return nil, fmt.Errorf("label '%s': bad number of lines '%v', expected non-negative integer", name, item.Value)
+		}
+		if other, ok := seen[lines]; ok {
+			return nil, fmt.Errorf("labels '%s' and '%s' have the same number of lines", other, name)
+		}
+		seen[lines] = name
+		s.labels = append(s.labels, sizeLabel{name: name, lines: lines})
+	}
+	sort.Slice(s.labels, func(i, j int) bool { return s.labels[i].lines < s.labels[j].lines })
+	return &s, nil
+}
@@ -0,0 +1,226 @@
+package mappings
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v45/github"
+	"gopkg.in/yaml.v2"
+)
+
+// testsKey is the mappings file key of the test cases section.
+const testsKey = "tests"
+
+type (
+	// TestCase is an example pull request and the labels expected for it. 'from-fork' conditions match only if
+	// FromFork is set. Teams are the teams ('org/team') the author is a member of, it isn't a member of any other.
+	TestCase struct {
+		Name       string     `yaml:"name"`
+		Files      []TestFile `yaml:"files"`
+		Title      string     `yaml:"title"`
+		Body       string     `yaml:"body"`
+		HeadBranch string     `yaml:"head-branch"`
+		BaseBranch string     `yaml:"base-branch"`
+		Author     string     `yaml:"author"`
+		FromFork   *bool      `yaml:"from-fork"`
+		Teams      []string   `yaml:"teams"`
+		Labels     []string   `yaml:"labels"`
+	}
+	// TestFile is a changed file of a test case, either a path or a mapping with the path and the details that
+	// status qualified patterns, 'patch' conditions and size labels match.
+	TestFile struct {
+		Name         string `yaml:"name"`
+		Status       string `yaml:"status"`
+		PreviousName string `yaml:"previous-name"`
+		Patch        string `yaml:"patch"`
+		Changes      int    `yaml:"changes"`
+	}
+	// TestFailure is a test case which labels don't match the expected ones.
+	TestFailure struct {
+		Case TestCase
+		// Missing are the expected labels that didn't match, Unexpected are the matched labels that aren't expected.
+		Missing    []string
+		Unexpected []string
+		Err        error
+	}
+)
+
+// Tests returns the test cases of the mappings file 'tests' section.
+func (ms Mappings) Tests() []TestCase {
+	return ms.tests
+}
+
+// Verify runs the test cases through MatchedLabels and returns the failed ones. Team membership is looked up in
+// the test case teams, not via Teams.
+func (ms Mappings) Verify(cases []TestCase) []TestFailure {
+	var failures []TestFailure
+	for _, c := range cases {
+		teams, err := newTeams(c.Teams)
+		if err != nil {
+			failures = append(failures, TestFailure{Case: c, Err: err})
+			continue
+		}
+		ms.Teams = caseTeams(teams)
+		labels, err := ms.MatchedLabels(c.pullRequest(), c.commitFiles())
+		if err != nil {
+			failures = append(failures, TestFailure{Case: c, Err: err})
+			continue
+		}
+		missing, unexpected := diffLabels(c.Labels, labels)
+		if len(missing) > 0 || len(unexpected) > 0 {
+			failures = append(failures, TestFailure{Case: c, Missing: missing, Unexpected: unexpected})
+		}
+	}
+	return failures
+}
+
+// ParseTests parses a test cases file: either a list of test cases or a document with 'tests' section.
+func ParseTests(conf []byte) ([]TestCase, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(conf, &doc); err != nil {
+		return nil, fmt.Errorf("test cases unmarshaling: %v", err)
+	}
+	if isMapping(doc) {
+		var v struct {
+			Tests interface{} `yaml:"tests"`
+		}
+		if err := decodeMapping(doc, &v); err != nil {
+			return nil, fmt.Errorf("test cases: %v", err)
+		}
+		doc = v.Tests
+	}
+	cases, err := parseTests(doc)
+	if err == nil && len(cases) == 0 {
+		err = errors.New("no test cases")
+	}
+	return cases, err
+}
+
+func parseTests(value interface{}) ([]TestCase, error) {
+	var cases []TestCase
+	if err := decodeMapping(value, &cases); err != nil {
+		return nil, fmt.Errorf("test cases: %v", err)
+	}
+	for i, c := range cases {
+		if c.Name == "" {
+			cases[i].Name = fmt.Sprintf("test case #%d", i+1)
+		}
+		if _, err := newTeams(c.Teams); err != nil {
+			return nil, fmt.Errorf("test case '%s': %v", cases[i].Name, err)
+		}
+		for _, f := range c.Files {
+			if f.Name == "" {
+				return nil, fmt.Errorf("test case '%s': file without name", cases[i].Name)
+			}
+			if f.Status != "" && !isFileStatus(f.Status) {
+				return nil, fmt.Errorf("test case '%s': file '%s': bad status '%s'", cases[i].Name, f.Name, f.Status)
+			}
+		}
+	}
+	return cases, nil
+}
+
+func isFileStatus(status string) bool {
+	for _, s := range fileStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML decodes a test case file from a path or from a mapping.
+func (f *TestFile) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&f.Name); err == nil {
+		return nil
+	}
+	type plain TestFile
+	return unmarshal((*plain)(f))
+}
+
+// FileNames returns the names of the test case files.
+func (c TestCase) FileNames() []string {
+	names := make([]string, 0, len(c.Files))
+	for _, f := range c.Files {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func (c TestCase) pullRequest() *github.PullRequest {
+	pull := &github.PullRequest{
+		Title: github.String(c.Title),
+		Body:  github.String(c.Body),
+		Head:  &github.PullRequestBranch{Ref: github.String(c.HeadBranch)},
+		Base:  &github.PullRequestBranch{Ref: github.String(c.BaseBranch)},
+	}
+	if c.Author != "" {
+		pull.User = &github.User{Login: github.String(c.Author)}
+	}
+	if c.FromFork != nil {
+		head := "owner/repository"
+		if *c.FromFork {
+			head = "fork/repository"
+		}
+		pull.Base.Repo = &github.Repository{FullName: github.String("owner/repository")}
+		pull.Head.Repo = &github.Repository{FullName: github.String(head)}
+	}
+	return pull
+}
+
+func (c TestCase) commitFiles() []*github.CommitFile {
+	files := make([]*github.CommitFile, 0, len(c.Files))
+	for _, f := range c.Files {
+		file := &github.CommitFile{Filename: github.String(f.Name)}
+		if f.Status != "" {
+			file.Status = github.String(f.Status)
+		}
+		if f.PreviousName != "" {
+			file.PreviousFilename = github.String(f.PreviousName)
+		}
+		if f.Patch != "" {
+			file.Patch = github.String(f.Patch)
+		}
+		if f.Changes != 0 {
+			file.Changes = github.Int(f.Changes)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// caseTeams are the teams of a test case author.
+type caseTeams teamCondition
+
+func (ts caseTeams) IsTeamMember(org, slug, _ string) (bool, error) {
+	for _, tm := range ts {
+		if strings.EqualFold(tm.org, org) && strings.EqualFold(tm.slug, slug) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func diffLabels(expected, got []string) (missing, unexpected []string) {
+	gotSet := make(map[string]bool, len(got))
+	for _, v := range got {
+		gotSet[v] = true
+	}
+	expectedSet := make(map[string]bool, len(expected))
+	for _, v := range expected {
+		expectedSet[v] = true
+		if !gotSet[v] {
+			missing = append(missing, v)
+		}
+	}
+	for _, v := range got {
+		if !expectedSet[v] {
+			unexpected = append(unexpected, v)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
@@ -0,0 +1,164 @@
+package mappings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappings_Verify(t *testing.T) {
+	conf := []byte(`
+collectors:
+  - collectors/**/*
+python.d:
+  - collectors/python.d.plugin/**/*
+type/fix:
+  title: ^fix
+tests:
+  - name: python.d module fix
+    title: "fix: apache module"
+    files:
+      - collectors/python.d.plugin/apache/apache.chart.py
+    labels: [type/fix, python.d, collectors]
+  - name: apps.plugin is not python.d
+    files:
+      - collectors/apps.plugin/apps.c
+    labels: [collectors, python.d]
+  - files:
+      - docs/guides/install.md
+    labels: [docs]
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+	require.Len(t, ms.Tests(), 3)
+
+	failures := ms.Verify(ms.Tests())
+
+	require.Len(t, failures, 2)
+	assert.Equal(t, "apps.plugin is not python.d", failures[0].Case.Name)
+	assert.Equal(t, []string{"python.d"}, failures[0].Missing)
+	assert.Empty(t, failures[0].Unexpected)
+	assert.Equal(t, "test case #3", failures[1].Case.Name)
+	assert.Equal(t, []string{"docs"}, failures[1].Missing)
+}
+
+func TestMappings_Verify_ReportsUnexpectedLabels(t *testing.T) {
+	ms, err := Parse([]byte("collectors:\n  - collectors/**/*\ntype/fix:\n  title: ^fix\n"))
+	require.NoError(t, err)
+
+	failures := ms.Verify([]TestCase{{Title: "fix: crash", Files: []TestFile{{Name: "collectors/apps.plugin/apps.c"}}}})
+
+	require.Len(t, failures, 1)
+	assert.Empty(t, failures[0].Missing)
+	assert.Equal(t, []string{"collectors", "type/fix"}, failures[0].Unexpected)
+}
+
+func TestMappings_Verify_FileDetails(t *testing.T) {
+	conf := []byte(`
+new-collector:
+  - added:collectors/**/*
+breaking:
+  patch:
+    removed: ^func [A-Z]
+size:
+  labels:
+    size/S: 0
+    size/L: 100
+tests:
+  - name: new collector
+    files:
+      - name: collectors/new.plugin/new.c
+        status: added
+        changes: 120
+    labels: [new-collector, size/L]
+  - name: removed exported function
+    files:
+      - name: pkg/api.go
+        status: modified
+        patch: "@@ -1,2 +1,1 @@\n-func Exported() {}\n"
+        changes: 1
+    labels: [breaking, size/S]
+  - name: renamed file is not added
+    files:
+      - name: collectors/new.plugin/new.c
+        status: renamed
+        previous-name: collectors/old.plugin/old.c
+    labels: [size/S]
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	assert.Empty(t, ms.Verify(ms.Tests()))
+}
+
+func TestMappings_Verify_AuthorTeams(t *testing.T) {
+	conf := []byte(`
+team/infra:
+  author-team: "@netdata/infra"
+bots:
+  author: dependabot*
+tests:
+  - name: bot is not a team member
+    author: dependabot[bot]
+    labels: [bots]
+  - name: team member
+    author: ilyam8
+    teams: [netdata/infra]
+    labels: [team/infra]
+  - name: other team member
+    author: ilyam8
+    teams: ["@netdata/agent"]
+`)
+	ms, err := Parse(conf)
+	require.NoError(t, err)
+
+	assert.Empty(t, ms.Verify(ms.Tests()))
+}
+
+func TestMappings_Verify_FromFork(t *testing.T) {
+	ms, err := Parse([]byte("external:\n  from-fork: true\ninternal:\n  from-fork: false\n"))
+	require.NoError(t, err)
+	fork, notFork := true, false
+
+	failures := ms.Verify([]TestCase{
+		{Name: "fork", FromFork: &fork, Labels: []string{"external"}},
+		{Name: "not a fork", FromFork: &notFork, Labels: []string{"internal"}},
+		{Name: "unknown", Files: []TestFile{{Name: "main.go"}}},
+	})
+
+	assert.Empty(t, failures)
+}
+
+func TestParseTests(t *testing.T) {
+	tests := map[string]struct {
+		input     string
+		wantCases int
+		wantErr   bool
+	}{
+		"list":                {input: "- files: [a.go]\n  labels: [go]\n- files: [b.go]\n", wantCases: 2},
+		"tests section":       {input: "tests:\n  - files: [a.go]\n    labels: [go]\n", wantCases: 1},
+		"no test cases":       {input: "tests: []\n", wantErr: true},
+		"unknown option":      {input: "- files: [a.go]\n  label: [go]\n", wantErr: true},
+		"unknown section":     {input: "cases:\n  - files: [a.go]\n", wantErr: true},
+		"invalid yaml":        {input: "- files: [a.go\n", wantErr: true},
+		"file details":        {input: "- files:\n    - a.go\n    - {name: b.go, status: added}\n", wantCases: 1},
+		"bad team":            {input: "- author: ilyam8\n  teams: [infra]\n", wantErr: true},
+		"bad file status":     {input: "- files:\n    - {name: b.go, status: new}\n", wantErr: true},
+		"file without name":   {input: "- files:\n    - {status: added}\n", wantErr: true},
+		"unknown file option": {input: "- files:\n    - {name: b.go, additions: 1}\n", wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cases, err := ParseTests([]byte(test.input))
+
+			if !test.wantErr {
+				require.NoError(t, err)
+				assert.Len(t, cases, test.wantCases)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
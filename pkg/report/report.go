@@ -0,0 +1,125 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ilyam8/periodic-pr-labeler/pkg/labeling"
+)
+
+// Report collects the labeling results of pull requests. It is safe for concurrent use.
+type Report struct {
+	DryRun bool
+	mu     sync.Mutex
+	pulls  []labeling.PullRequestReport
+}
+
+// Report adds the result of a pull request.
+func (r *Report) Report(pull labeling.PullRequestReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pulls = append(r.pulls, pull)
+}
+
+// PullRequests returns the results ordered by repository and pull request number.
+func (r *Report) PullRequests() []labeling.PullRequestReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pulls := append([]labeling.PullRequestReport(nil), r.pulls...)
+	sort.SliceStable(pulls, func(i, j int) bool {
+		if pulls[i].Repository != pulls[j].Repository {
+			return pulls[i].Repository < pulls[j].Repository
+		}
+		return pulls[i].Number < pulls[j].Number
+	})
+	return pulls
+}
+
+// WriteJSON writes the report in JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	v := struct {
+		DryRun       bool                         `json:"dry_run"`
+		PullRequests []labeling.PullRequestReport `json:"pull_requests"`
+	}{
+		DryRun:       r.DryRun,
+		PullRequests: r.PullRequests(),
+	}
+	if v.PullRequests == nil {
+		v.PullRequests = []labeling.PullRequestReport{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// WriteMarkdown writes the report in Markdown: a table of pull requests followed by the matched files per label.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("## Labeler report")
+	if r.DryRun {
+		b.WriteString(" (dry run)")
+	}
+	b.WriteString("\n\n")
+
+	pulls := r.PullRequests()
+	if len(pulls) == 0 {
+		b.WriteString("No pull requests.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	b.WriteString("| Pull request | Added | Removed | Status |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, pull := range pulls {
+		fmt.Fprintf(&b, "| %s#%d | %s | %s | %s |\n",
+			pull.Repository, pull.Number, codeList(pull.Added), codeList(pull.Removed), status(pull))
+	}
+
+	for _, pull := range pulls {
+		if len(pull.Files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details><summary>%s#%d matched files</summary>\n\n", pull.Repository, pull.Number)
+		for _, label := range pull.Labels {
+			if files, ok := pull.Files[label]; ok {
+				fmt.Fprintf(&b, "- `%s`: %s\n", label, codeList(files))
+			}
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func status(pull labeling.PullRequestReport) string {
+	switch {
+	case pull.Error != "":
+		return "failed: " + escape(pull.Error)
+	case pull.Skipped != "":
+		return "skipped: " + escape(pull.Skipped)
+	case len(pull.Added) > 0 || len(pull.Removed) > 0:
+		return "changed"
+	case len(pull.Labels) > 0:
+		return "has all"
+	}
+	return "no match"
+}
+
+func codeList(values []string) string {
+	items := make([]string, 0, len(values))
+	for _, v := range values {
+		items = append(items, "`"+escape(v)+"`")
+	}
+	return strings.Join(items, ", ")
+}
+
+// escape escapes the characters that break a Markdown table row.
+func escape(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ilyam8/periodic-pr-labeler/pkg/labeling"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func prepareReport() *Report {
+	r := &Report{DryRun: true}
+	r.Report(labeling.PullRequestReport{Repository: "owner/name", Number: 3, Error: "mock error"})
+	r.Report(labeling.PullRequestReport{
+		Repository: "owner/name",
+		Number:     1,
+		Labels:     []string{"collectors", "type/fix"},
+		Files:      map[string][]string{"collectors": {"collectors/apps.plugin/apps.c", "collectors/README.md"}},
+		Added:      []string{"collectors", "type/fix"},
+		Removed:    []string{"docs"},
+	})
+	r.Report(labeling.PullRequestReport{Repository: "owner/name", Number: 2, Skipped: "draft"})
+	r.Report(labeling.PullRequestReport{Repository: "owner/another", Number: 5})
+	return r
+}
+
+func TestReport_PullRequests(t *testing.T) {
+	var numbers []int
+	for _, pull := range prepareReport().PullRequests() {
+		numbers = append(numbers, pull.Number)
+	}
+	assert.Equal(t, []int{5, 1, 2, 3}, numbers)
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, prepareReport().WriteJSON(&buf))
+
+	var v struct {
+		DryRun       bool                         `json:"dry_run"`
+		PullRequests []labeling.PullRequestReport `json:"pull_requests"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &v))
+	assert.True(t, v.DryRun)
+	assert.Equal(t, prepareReport().PullRequests(), v.PullRequests)
+
+	buf.Reset()
+	require.NoError(t, (&Report{}).WriteJSON(&buf))
+	assert.JSONEq(t, `{"dry_run": false, "pull_requests": []}`, buf.String())
+}
+
+func TestReport_WriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, prepareReport().WriteMarkdown(&buf))
+
+	assert.Equal(t, "## Labeler report (dry run)\n"+
+		"\n"+
+		"| Pull request | Added | Removed | Status |\n"+
+		"|---|---|---|---|\n"+
+		"| owner/another#5 |  |  | no match |\n"+
+		"| owner/name#1 | `collectors`, `type/fix` | `docs` | changed |\n"+
+		"| owner/name#2 |  |  | skipped: draft |\n"+
+		"| owner/name#3 |  |  | failed: mock error |\n"+
+		"\n"+
+		"<details><summary>owner/name#1 matched files</summary>\n"+
+		"\n"+
+		"- `collectors`: `collectors/apps.plugin/apps.c`, `collectors/README.md`\n"+
+		"\n"+
+		"</details>\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, (&Report{}).WriteMarkdown(&buf))
+	assert.Equal(t, "## Labeler report\n\nNo pull requests.\n", buf.String())
+}
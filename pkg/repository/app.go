@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// jwtLifetime is the lifetime of GitHub App JWT, GitHub allows at most 10 minutes.
+	jwtLifetime = 9 * time.Minute
+	// installationTokenEarlyExpiry is how long before the expiry an installation token is refreshed.
+	installationTokenEarlyExpiry = 5 * time.Minute
+)
+
+func newTokenSource(conf Config) (oauth2.TokenSource, error) {
+	if conf.AppID == 0 {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: conf.Token}), nil
+	}
+	src, err := newInstallationTokenSource(conf)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSourceWithExpiry(nil, src, installationTokenEarlyExpiry), nil
+}
+
+// installationTokenSource exchanges GitHub App JWT for an installation access token.
+type installationTokenSource struct {
+	installationID int64
+	client         *github.Client
+}
+
+func newInstallationTokenSource(conf Config) (*installationTokenSource, error) {
+	if conf.InstallationID == 0 {
+		return nil, errors.New("GitHub App installation ID not set")
+	}
+	key, err := parsePrivateKey(conf.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing GitHub App private key: %v", err)
+	}
+	transport := &jwtTransport{
+		appID: conf.AppID,
+		key:   key,
+		base:  http.DefaultTransport,
+		now:   time.Now,
+	}
+	client, err := newClient(conf, &http.Client{Transport: transport})
+	if err != nil {
+		return nil, err
+	}
+	return &installationTokenSource{installationID: conf.InstallationID, client: client}, nil
+}
+
+// Token creates a new installation access token.
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	tok, _, err := s.client.Apps.CreateInstallationToken(context.Background(), s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating GitHub App installation token: %v", err)
+	}
+	return &oauth2.Token{AccessToken: tok.GetToken(), Expiry: tok.GetExpiresAt()}, nil
+}
+
+// jwtTransport authenticates requests as a GitHub App using a JWT signed with the app private key.
+type jwtTransport struct {
+	appID int64
+	key   *rsa.PrivateKey
+	base  http.RoundTripper
+	now   func() time.Time
+}
+
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.jwt()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+func (t *jwtTransport) jwt() (string, error) {
+	now := t.now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		// issued in the past to allow for clock drift
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": strconv.FormatInt(t.appID, 10),
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, t.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("signing GitHub App JWT: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return rsaKey, nil
+}
@@ -3,24 +3,77 @@ package repository
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v45/github"
 	"golang.org/x/oauth2"
 )
 
-func newGitHubClient(token string) *github.Client {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	tc := oauth2.NewClient(context.Background(), ts)
-	return github.NewClient(tc)
+func newGitHubClient(conf Config) (*github.Client, error) {
+	ts, err := newTokenSource(conf)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(conf, oauth2.NewClient(context.Background(), ts))
+}
+
+func newClient(conf Config, httpClient *http.Client) (*github.Client, error) {
+	if isGitHubDotCom(conf.BaseURL) {
+		return github.NewClient(httpClient), nil
+	}
+	uploadURL := conf.UploadURL
+	if uploadURL == "" {
+		uploadURL = enterpriseUploadURL(conf.BaseURL)
+	}
+	return github.NewEnterpriseClient(conf.BaseURL, uploadURL, httpClient)
+}
+
+func isGitHubDotCom(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	return baseURL == "" || err == nil && u.Host == "api.github.com"
+}
+
+// enterpriseUploadURL derives GitHub Enterprise Server upload URL from its API URL ("https://host/api/v3").
+func enterpriseUploadURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(baseURL, "/api/v3") {
+		return strings.TrimSuffix(baseURL, "/api/v3") + "/api/uploads"
+	}
+	return baseURL
 }
 
+const (
+	// defaultPerPage is the maximum page size GitHub allows for list requests.
+	defaultPerPage = 100
+	// maxPullRequestFiles is the maximum number of files the pull request files endpoint returns.
+	maxPullRequestFiles = 3000
+	// defaultBackoff is the delay before the first retry of a failed request, it doubles with every attempt.
+	defaultBackoff = time.Second
+)
+
 // New creates new Repository.
-func New(conf Config) *Repository {
-	return &Repository{
-		owner:  conf.Owner,
-		name:   conf.Name,
-		Client: newGitHubClient(conf.Token),
+func New(conf Config) (*Repository, error) {
+	client, err := newGitHubClient(conf)
+	if err != nil {
+		return nil, fmt.Errorf("creating GitHub client: %v", err)
+	}
+	perPage := conf.PerPage
+	if perPage <= 0 {
+		perPage = defaultPerPage
 	}
+	return &Repository{
+		owner:      conf.Owner,
+		name:       conf.Name,
+		perPage:    perPage,
+		maxRetries: conf.MaxRetries,
+		backoff:    defaultBackoff,
+		sleep:      time.Sleep,
+		teams:      newTeamsCache(),
+		Client:     client,
+	}, nil
 }
 
 // Config is Repository configuration.
@@ -28,15 +81,37 @@ type Config struct {
 	Owner string
 	Name  string
 	Token string
+	// AppID, InstallationID and PrivateKey (PEM encoded) authenticate as a GitHub App installation instead of Token.
+	AppID          int64
+	InstallationID int64
+	PrivateKey     []byte
+	// BaseURL is GitHub API URL, github.com is used if not set.
+	BaseURL string
+	// UploadURL is GitHub uploads URL, derived from BaseURL if not set.
+	UploadURL string
+	PerPage   int
+	// MaxRetries is the number of times a failed request is retried.
+	MaxRetries int
 }
 
 // Repository represents GitHub repository.
 type Repository struct {
-	owner string
-	name  string
+	owner      string
+	name       string
+	perPage    int
+	maxRetries int
+	backoff    time.Duration
+	sleep      func(time.Duration)
+	teams      *teamsCache
 	*github.Client
 }
 
+// WithRepository returns a copy of the Repository that targets another repository, sharing the GitHub client.
+func (r Repository) WithRepository(owner, name string) *Repository {
+	r.owner, r.name = owner, name
+	return &r
+}
+
 // Owner is repository owner.
 func (r Repository) Owner() string {
 	return r.owner
@@ -49,20 +124,40 @@ func (r Repository) Name() string {
 
 // FileContent returns content of a single file. If filepath doesn't reference to a file it returns nil.
 func (r Repository) FileContent(filepath string) (*github.RepositoryContent, error) {
-	content, _, _, err := r.Repositories.GetContents(context.TODO(), r.Owner(), r.Name(), filepath, nil)
+	var content *github.RepositoryContent
+	err := r.retry(func() (resp *github.Response, err error) {
+		content, _, resp, err = r.Repositories.GetContents(context.TODO(), r.Owner(), r.Name(), filepath, nil)
+		return resp, err
+	})
 	if content == nil && err == nil {
 		err = fmt.Errorf("'%s' is not a file", filepath)
 	}
 	return content, err
 }
 
-// OpenPullRequests lists all the pull requests in the open state.
-func (r Repository) OpenPullRequests() ([]*github.PullRequest, error) {
-	opts := &github.PullRequestListOptions{State: "open", Sort: "updated"}
+// OpenPullRequests lists the pull requests in the open state, the most recently updated first. If since is not zero,
+// only the pull requests updated since then are listed and paging stops at the first older pull request.
+func (r Repository) OpenPullRequests(since time.Time) ([]*github.PullRequest, error) {
+	opts := &github.PullRequestListOptions{
+		State: "open",
+		Sort:  "updated",
+		// sorting by update time is ascending by default
+		Direction:   "desc",
+		ListOptions: github.ListOptions{PerPage: r.perPage},
+	}
 	var pulls []*github.PullRequest
 	for {
-		list, resp, err := r.PullRequests.List(context.TODO(), r.Owner(), r.Name(), opts)
-		pulls = append(pulls, list...)
+		var list []*github.PullRequest
+		resp, err := r.retryResp(func() (resp *github.Response, err error) {
+			list, resp, err = r.PullRequests.List(context.TODO(), r.Owner(), r.Name(), opts)
+			return resp, err
+		})
+		for _, pull := range list {
+			if !since.IsZero() && pull.GetUpdatedAt().Before(since) {
+				return pulls, nil
+			}
+			pulls = append(pulls, pull)
+		}
 		if err != nil || resp.NextPage == 0 {
 			return pulls, err
 		}
@@ -70,14 +165,164 @@ func (r Repository) OpenPullRequests() ([]*github.PullRequest, error) {
 	}
 }
 
-// PullRequestModifiedFiles lists the files in a pull request.
-func (r Repository) PullRequestModifiedFiles(number int) ([]*github.CommitFile, error) {
-	files, _, err := r.PullRequests.ListFiles(context.Background(), r.Owner(), r.Name(), number, nil)
-	return files, err
+// PullRequest returns a single pull request in any state.
+func (r Repository) PullRequest(number int) (*github.PullRequest, error) {
+	var pull *github.PullRequest
+	err := r.retry(func() (resp *github.Response, err error) {
+		pull, resp, err = r.PullRequests.Get(context.Background(), r.Owner(), r.Name(), number)
+		return resp, err
+	})
+	return pull, err
+}
+
+// Tree lists the paths of the files on the repository default branch. truncated reports whether GitHub cut the list
+// short, it does for very big repositories.
+func (r Repository) Tree() (paths []string, truncated bool, err error) {
+	var repo *github.Repository
+	err = r.retry(func() (resp *github.Response, err error) {
+		repo, resp, err = r.Repositories.Get(context.Background(), r.Owner(), r.Name())
+		return resp, err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	var tree *github.Tree
+	err = r.retry(func() (resp *github.Response, err error) {
+		tree, resp, err = r.Git.GetTree(context.Background(), r.Owner(), r.Name(), repo.GetDefaultBranch(), true)
+		return resp, err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	for _, entry := range tree.Entries {
+		if entry.GetType() == "blob" {
+			paths = append(paths, entry.GetPath())
+		}
+	}
+	return paths, tree.GetTruncated(), nil
+}
+
+// PullRequestModifiedFiles lists the files in a pull request. The pull request files endpoint returns at most
+// 3000 files, truncated reports whether the pull request has more. No other endpoint lists more changed files:
+// the compare endpoint returns at most 300.
+func (r Repository) PullRequestModifiedFiles(pull *github.PullRequest) (files []*github.CommitFile, truncated bool, err error) {
+	files, err = r.pullRequestFiles(pull.GetNumber())
+	if err != nil || len(files) < maxPullRequestFiles {
+		return files, false, err
+	}
+
+	// list endpoint doesn't report the number of changed files, only the single pull request endpoint does
+	err = r.retry(func() (resp *github.Response, err error) {
+		pull, resp, err = r.PullRequests.Get(context.Background(), r.Owner(), r.Name(), pull.GetNumber())
+		return resp, err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return files, pull.GetChangedFiles() > len(files), nil
+}
+
+func (r Repository) pullRequestFiles(number int) ([]*github.CommitFile, error) {
+	opts := &github.ListOptions{PerPage: r.perPage}
+	var files []*github.CommitFile
+	for {
+		var list []*github.CommitFile
+		resp, err := r.retryResp(func() (resp *github.Response, err error) {
+			list, resp, err = r.PullRequests.ListFiles(context.Background(), r.Owner(), r.Name(), number, opts)
+			return resp, err
+		})
+		files = append(files, list...)
+		if err != nil || resp.NextPage == 0 {
+			return files, err
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// OrganizationRepositories lists slugs ("owner/name") of all the not archived repositories of an organization.
+func (r Repository) OrganizationRepositories(org string) ([]string, error) {
+	opts := &github.RepositoryListByOrgOptions{ListOptions: github.ListOptions{PerPage: r.perPage}}
+	var slugs []string
+	for {
+		var list []*github.Repository
+		resp, err := r.retryResp(func() (resp *github.Response, err error) {
+			list, resp, err = r.Repositories.ListByOrg(context.Background(), org, opts)
+			return resp, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range list {
+			if !repo.GetArchived() {
+				slugs = append(slugs, repo.GetFullName())
+			}
+		}
+		if resp.NextPage == 0 {
+			return slugs, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// Labels lists all the repository labels.
+func (r Repository) Labels() ([]*github.Label, error) {
+	opts := &github.ListOptions{PerPage: r.perPage}
+	var labels []*github.Label
+	for {
+		var list []*github.Label
+		resp, err := r.retryResp(func() (resp *github.Response, err error) {
+			list, resp, err = r.Issues.ListLabels(context.Background(), r.Owner(), r.Name(), opts)
+			return resp, err
+		})
+		labels = append(labels, list...)
+		if err != nil || resp.NextPage == 0 {
+			return labels, err
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// CreateLabel creates a repository label.
+func (r Repository) CreateLabel(label *github.Label) error {
+	return r.retry(func() (resp *github.Response, err error) {
+		_, resp, err = r.Issues.CreateLabel(context.Background(), r.Owner(), r.Name(), label)
+		return resp, err
+	})
+}
+
+// EditLabel updates color and description of a repository label.
+func (r Repository) EditLabel(label *github.Label) error {
+	return r.retry(func() (resp *github.Response, err error) {
+		_, resp, err = r.Issues.EditLabel(context.Background(), r.Owner(), r.Name(), label.GetName(), label)
+		return resp, err
+	})
 }
 
 // AddLabelsToPullRequest adds labels to a pull request.
 func (r Repository) AddLabelsToPullRequest(number int, labels []string) error {
-	_, _, err := r.Issues.AddLabelsToIssue(context.Background(), r.Owner(), r.Name(), number, labels)
-	return err
+	return r.retry(func() (resp *github.Response, err error) {
+		_, resp, err = r.Issues.AddLabelsToIssue(context.Background(), r.Owner(), r.Name(), number, labels)
+		return resp, err
+	})
+}
+
+// RemoveLabelFromPullRequest removes a label from a pull request.
+func (r Repository) RemoveLabelFromPullRequest(number int, label string) error {
+	return r.retry(func() (*github.Response, error) {
+		return r.Issues.RemoveLabelForIssue(context.Background(), r.Owner(), r.Name(), number, label)
+	})
+}
+
+// RateLimit returns the current core API rate limit. Checking the rate limit doesn't count against it.
+func (r Repository) RateLimit() (*github.Rate, error) {
+	var limits *github.RateLimits
+	err := r.retry(func() (resp *github.Response, err error) {
+		limits, resp, err = r.RateLimits(context.Background())
+		return resp, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return limits.GetCore(), nil
 }
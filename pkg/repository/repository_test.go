@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_PullRequestModifiedFiles(t *testing.T) {
+	tests := map[string]struct {
+		files         int
+		changedFiles  int
+		wantFiles     int
+		wantTruncated bool
+	}{
+		"single page":          {files: 42, wantFiles: 42},
+		"several pages":        {files: 250, wantFiles: 250},
+		"files endpoint limit": {files: 3000, changedFiles: 3000, wantFiles: 3000},
+		"truncated":            {files: 3000, changedFiles: 3500, wantFiles: 3000, wantTruncated: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var getCalls int
+			mux := http.NewServeMux()
+			mux.HandleFunc("/repos/owner/name/pulls/1/files", func(w http.ResponseWriter, r *http.Request) {
+				writePage(w, r, test.files, 0)
+			})
+			mux.HandleFunc("/repos/owner/name/pulls/1", func(w http.ResponseWriter, r *http.Request) {
+				getCalls++
+				writeJSON(w, github.PullRequest{Number: github.Int(1), ChangedFiles: github.Int(test.changedFiles)})
+			})
+			r, _ := prepareRepository(t, mux)
+
+			files, truncated, err := r.PullRequestModifiedFiles(&github.PullRequest{Number: github.Int(1)})
+
+			require.NoError(t, err)
+			assert.Len(t, files, test.wantFiles)
+			assert.Equal(t, test.wantTruncated, truncated)
+			if test.files < maxPullRequestFiles {
+				assert.Zero(t, getCalls)
+			}
+		})
+	}
+}
+
+func TestRepository_OpenPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/name/pulls", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 2 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=2>; rel="next"`, r.URL.Path))
+			writeJSON(w, []*github.PullRequest{{Number: github.Int(1)}, {Number: github.Int(2)}})
+			return
+		}
+		writeJSON(w, []*github.PullRequest{{Number: github.Int(3)}})
+	})
+	r, _ := prepareRepository(t, mux)
+
+	pulls, err := r.OpenPullRequests(time.Time{})
+
+	require.NoError(t, err)
+	assert.Len(t, pulls, 3)
+}
+
+func TestRepository_OpenPullRequests_UpdatedSince(t *testing.T) {
+	now := time.Now()
+	updatedAgo := func(d time.Duration) *time.Time { t := now.Add(-d); return &t }
+	var pages int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/name/pulls", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "updated", r.URL.Query().Get("sort"))
+		assert.Equal(t, "desc", r.URL.Query().Get("direction"))
+		pages++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 2 {
+			page = 1
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next"`, r.URL.Path, page+1))
+		if page < 2 {
+			writeJSON(w, []*github.PullRequest{
+				{Number: github.Int(1), UpdatedAt: updatedAgo(time.Hour)},
+				{Number: github.Int(2), UpdatedAt: updatedAgo(2 * time.Hour)},
+			})
+			return
+		}
+		writeJSON(w, []*github.PullRequest{
+			{Number: github.Int(3), UpdatedAt: updatedAgo(3 * time.Hour)},
+			{Number: github.Int(4), UpdatedAt: updatedAgo(5 * time.Hour)},
+		})
+	})
+	r, _ := prepareRepository(t, mux)
+
+	pulls, err := r.OpenPullRequests(now.Add(-4 * time.Hour))
+
+	require.NoError(t, err)
+	require.Len(t, pulls, 3)
+	assert.Equal(t, 3, pulls[2].GetNumber())
+	assert.Equal(t, 2, pages)
+}
+
+func TestRepository_PullRequest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/name/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, &github.PullRequest{Number: github.Int(7), State: github.String("closed")})
+	})
+	r, _ := prepareRepository(t, mux)
+
+	pull, err := r.PullRequest(7)
+	require.NoError(t, err)
+	assert.Equal(t, 7, pull.GetNumber())
+	assert.Equal(t, "closed", pull.GetState())
+
+	_, err = r.PullRequest(8)
+	assert.Error(t, err)
+}
+
+func TestRepository_Tree(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/name", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, &github.Repository{DefaultBranch: github.String("master")})
+	})
+	mux.HandleFunc("/repos/owner/name/git/trees/master", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.URL.Query().Get("recursive"))
+		writeJSON(w, &github.Tree{
+			Entries: []*github.TreeEntry{
+				{Path: github.String("docs"), Type: github.String("tree")},
+				{Path: github.String("docs/README.md"), Type: github.String("blob")},
+				{Path: github.String("main.go"), Type: github.String("blob")},
+			},
+			Truncated: github.Bool(true),
+		})
+	})
+	r, _ := prepareRepository(t, mux)
+
+	paths, truncated, err := r.Tree()
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs/README.md", "main.go"}, paths)
+	assert.True(t, truncated)
+}
+
+func TestRepository_OrganizationRepositories(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/org/repos", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 2 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=2>; rel="next"`, r.URL.Path))
+			writeJSON(w, []*github.Repository{
+				{FullName: github.String("org/one")},
+				{FullName: github.String("org/archived"), Archived: github.Bool(true)},
+			})
+			return
+		}
+		writeJSON(w, []*github.Repository{{FullName: github.String("org/two")}})
+	})
+	r, _ := prepareRepository(t, mux)
+
+	slugs, err := r.OrganizationRepositories("org")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org/one", "org/two"}, slugs)
+}
+
+func TestRepository_WithRepository(t *testing.T) {
+	r, _ := prepareRepository(t, http.NotFoundHandler())
+
+	other := r.WithRepository("other", "repo")
+
+	assert.Equal(t, "other", other.Owner())
+	assert.Equal(t, "repo", other.Name())
+	assert.Equal(t, "owner", r.Owner())
+	assert.Same(t, r.Client, other.Client)
+}
+
+func TestNew_EnterpriseServer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/owner/name/pulls", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		writeJSON(w, []*github.PullRequest{{Number: github.Int(1)}})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	r, err := New(Config{Owner: "owner", Name: "name", Token: "token", BaseURL: srv.URL + "/api/v3"})
+	require.NoError(t, err)
+
+	assert.Equal(t, srv.URL+"/api/v3/", r.BaseURL.String())
+	assert.Equal(t, srv.URL+"/api/uploads/", r.UploadURL.String())
+
+	pulls, err := r.OpenPullRequests(time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, pulls, 1)
+}
+
+func TestNew_BaseURL(t *testing.T) {
+	tests := map[string]struct {
+		baseURL       string
+		uploadURL     string
+		wantBaseURL   string
+		wantUploadURL string
+		wantErr       bool
+	}{
+		"not set":               {wantBaseURL: "https://api.github.com/", wantUploadURL: "https://uploads.github.com/"},
+		"github.com":            {baseURL: "https://api.github.com", wantBaseURL: "https://api.github.com/", wantUploadURL: "https://uploads.github.com/"},
+		"enterprise api url":    {baseURL: "https://ghe.example.com/api/v3", wantBaseURL: "https://ghe.example.com/api/v3/", wantUploadURL: "https://ghe.example.com/api/uploads/"},
+		"enterprise host url":   {baseURL: "https://ghe.example.com", wantBaseURL: "https://ghe.example.com/api/v3/", wantUploadURL: "https://ghe.example.com/api/uploads/"},
+		"enterprise upload url": {baseURL: "https://ghe.example.com", uploadURL: "https://uploads.example.com/api/uploads", wantBaseURL: "https://ghe.example.com/api/v3/", wantUploadURL: "https://uploads.example.com/api/uploads/"},
+		"bad url":               {baseURL: "://ghe.example.com", wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := New(Config{BaseURL: test.baseURL, UploadURL: test.uploadURL})
+
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.wantBaseURL, r.BaseURL.String())
+			assert.Equal(t, test.wantUploadURL, r.UploadURL.String())
+		})
+	}
+}
+
+func prepareRepository(t *testing.T, handler http.Handler) (*Repository, *[]time.Duration) {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	r, err := New(Config{Owner: "owner", Name: "name", MaxRetries: 3})
+	require.NoError(t, err)
+	r.BaseURL, _ = url.Parse(srv.URL + "/")
+
+	var slept []time.Duration
+	r.sleep = func(d time.Duration) { slept = append(slept, d) }
+	return r, &slept
+}
+
+func writePage(w http.ResponseWriter, r *http.Request, total, offset int) {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	start := offset + (page-1)*perPage
+	end := start + perPage
+	if end >= total {
+		end = total
+	} else {
+		w.Header().Set("Link", fmt.Sprintf(`<%s?per_page=%d&page=%d>; rel="next"`, r.URL.Path, perPage, page+1))
+	}
+	writeJSON(w, makeCommitFiles(start, end))
+}
+
+func makeCommitFiles(start, end int) []*github.CommitFile {
+	files := make([]*github.CommitFile, 0, end-start)
+	for i := start; i < end; i++ {
+		files = append(files, &github.CommitFile{Filename: github.String(fmt.Sprintf("file%d", i))})
+	}
+	return files
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
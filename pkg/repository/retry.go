@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	log "github.com/sirupsen/logrus"
+)
+
+func (r Repository) retry(call func() (*github.Response, error)) error {
+	_, err := r.retryResp(call)
+	return err
+}
+
+// retryResp calls the GitHub API until the call succeeds, the error is not retryable or retries are exhausted.
+// Rate limit errors are retried after the rate limit resets, server errors are retried with exponential backoff.
+func (r Repository) retryResp(call func() (*github.Response, error)) (*github.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := call()
+		if err == nil || attempt >= r.maxRetries {
+			return resp, err
+		}
+		wait, ok := retryDelay(err, r.backoff<<attempt)
+		if !ok {
+			return resp, err
+		}
+		log.Warnf("%v (retrying in %s, attempt %d/%d)", err, wait, attempt+1, r.maxRetries)
+		r.sleep(wait)
+	}
+}
+
+func retryDelay(err error, backoff time.Duration) (time.Duration, bool) {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var respErr *github.ErrorResponse
+	switch {
+	case errors.As(err, &rateErr):
+		wait := time.Until(rateErr.Rate.Reset.Time) + time.Second
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	case errors.As(err, &abuseErr):
+		if abuseErr.RetryAfter != nil {
+			return *abuseErr.RetryAfter, true
+		}
+		return backoff, true
+	case errors.As(err, &respErr) && respErr.Response != nil:
+		switch code := respErr.Response.StatusCode; {
+		case code == http.StatusTooManyRequests:
+			if secs, err := strconv.Atoi(respErr.Response.Header.Get("Retry-After")); err == nil {
+				return time.Duration(secs) * time.Second, true
+			}
+			return backoff, true
+		case code >= http.StatusInternalServerError:
+			return backoff, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_RetriesServerErrorsWithBackoff(t *testing.T) {
+	var requests int
+	r, slept := prepareRepository(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests++; requests <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, []*github.PullRequest{})
+	}))
+
+	_, err := r.OpenPullRequests(time.Time{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *slept)
+}
+
+func TestRepository_WaitsForRateLimitReset(t *testing.T) {
+	var requests int
+	r, slept := prepareRepository(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests++; requests == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		writeJSON(w, []*github.PullRequest{})
+	}))
+
+	_, err := r.OpenPullRequests(time.Time{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	require.Len(t, *slept, 1)
+	assert.LessOrEqual(t, (*slept)[0], time.Second)
+}
+
+func TestRepository_WaitsForSecondaryRateLimitRetryAfter(t *testing.T) {
+	var requests int
+	r, slept := prepareRepository(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests++; requests == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusForbidden)
+			writeJSON(w, github.ErrorResponse{
+				Message:          "You have exceeded a secondary rate limit",
+				DocumentationURL: "https://docs.github.com/rest/overview/resources-in-the-rest-api#secondary-rate-limits",
+			})
+			return
+		}
+		writeJSON(w, []*github.PullRequest{})
+	}))
+
+	_, err := r.OpenPullRequests(time.Time{})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, []time.Duration{7 * time.Second}, *slept)
+}
+
+func TestRepository_DoesntRetryClientErrors(t *testing.T) {
+	var requests int
+	r, slept := prepareRepository(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	_, err := r.OpenPullRequests(time.Time{})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Empty(t, *slept)
+}
+
+func TestRepository_GivesUpWhenRetriesExhausted(t *testing.T) {
+	var requests int
+	r, slept := prepareRepository(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	_, err := r.OpenPullRequests(time.Time{})
+
+	assert.Error(t, err)
+	assert.Equal(t, 4, requests)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, *slept)
+}
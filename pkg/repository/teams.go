@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v45/github"
+)
+
+// teamsCache caches team memberships, it is shared by all the copies of a Repository.
+type teamsCache struct {
+	mu      sync.Mutex
+	members map[string]bool
+}
+
+func newTeamsCache() *teamsCache {
+	return &teamsCache{members: make(map[string]bool)}
+}
+
+func (c *teamsCache) get(key string) (member, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	member, ok = c.members[key]
+	return member, ok
+}
+
+func (c *teamsCache) set(key string, member bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.members[key] = member
+}
+
+// IsTeamMember reports whether the user is an active member of the organization team. Results are cached.
+func (r Repository) IsTeamMember(org, team, user string) (bool, error) {
+	key := strings.ToLower(org + "/" + team + "/" + user)
+	if member, ok := r.teams.get(key); ok {
+		return member, nil
+	}
+
+	var membership *github.Membership
+	err := r.retry(func() (resp *github.Response, err error) {
+		membership, resp, err = r.Teams.GetTeamMembershipBySlug(context.Background(), org, team, user)
+		return resp, err
+	})
+
+	var respErr *github.ErrorResponse
+	switch {
+	case errors.As(err, &respErr) && respErr.Response != nil && respErr.Response.StatusCode == http.StatusNotFound:
+		r.teams.set(key, false)
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	member := membership.GetState() == "active"
+	r.teams.set(key, member)
+	return member, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v45/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_IsTeamMember(t *testing.T) {
+	requests := make(map[string]int)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/org/teams/infra/memberships/", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/orgs/org/teams/infra/memberships/active":
+			writeJSON(w, github.Membership{State: github.String("active")})
+		case "/orgs/org/teams/infra/memberships/pending":
+			writeJSON(w, github.Membership{State: github.String("pending")})
+		case "/orgs/org/teams/infra/memberships/broken":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	r, _ := prepareRepository(t, mux)
+	other := r.WithRepository("org", "other")
+
+	tests := map[string]struct {
+		user       string
+		wantMember bool
+		wantErr    bool
+	}{
+		"active member":  {user: "active", wantMember: true},
+		"pending member": {user: "pending"},
+		"not a member":   {user: "stranger"},
+		"lookup error":   {user: "broken", wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, rs := range []*Repository{r, other, r} {
+				member, err := rs.IsTeamMember("org", "infra", test.user)
+
+				if test.wantErr {
+					assert.Error(t, err)
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, test.wantMember, member)
+			}
+		})
+	}
+
+	assert.Equal(t, map[string]int{
+		"/orgs/org/teams/infra/memberships/active":   1,
+		"/orgs/org/teams/infra/memberships/pending":  1,
+		"/orgs/org/teams/infra/memberships/stranger": 1,
+		"/orgs/org/teams/infra/memberships/broken":   3,
+	}, requests)
+}
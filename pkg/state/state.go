@@ -0,0 +1,111 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// expiration is how long a pull request is remembered after it was last seen.
+const expiration = 30 * 24 * time.Hour
+
+type (
+	// Store remembers the pull requests labeled by the previous runs. It is safe for concurrent use.
+	Store struct {
+		path  string
+		now   func() time.Time
+		mu    sync.Mutex
+		pulls map[string]*entry
+	}
+	entry struct {
+		HeadSHA      string    `json:"head_sha"`
+		PullHash     string    `json:"pull_hash"`
+		MappingsHash string    `json:"mappings_hash"`
+		SeenAt       time.Time `json:"seen_at"`
+	}
+	file struct {
+		PullRequests map[string]*entry `json:"pull_requests"`
+	}
+)
+
+// Load reads the state file. A missing file is an empty state, it is created on Save.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, now: time.Now, pulls: make(map[string]*entry)}
+	bs, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(bs, &f); err != nil {
+		return nil, fmt.Errorf("state file '%s': %v", path, err)
+	}
+	for k, v := range f.PullRequests {
+		if v != nil {
+			s.pulls[k] = v
+		}
+	}
+	return s, nil
+}
+
+// Unchanged reports whether the pull request was labeled at the same head commit, with the same fields (pullHash)
+// and with the same mappings.
+func (s *Store) Unchanged(repo string, number int, headSHA, pullHash, mappingsHash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.pulls[key(repo, number)]
+	if !ok || headSHA == "" || e.HeadSHA != headSHA || e.PullHash != pullHash || e.MappingsHash != mappingsHash {
+		return false
+	}
+	e.SeenAt = s.now()
+	return true
+}
+
+// Record remembers that the pull request was labeled at the head commit, with the fields (pullHash) and the mappings.
+func (s *Store) Record(repo string, number int, headSHA, pullHash, mappingsHash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pulls[key(repo, number)] = &entry{
+		HeadSHA:      headSHA,
+		PullHash:     pullHash,
+		MappingsHash: mappingsHash,
+		SeenAt:       s.now(),
+	}
+}
+
+// Save writes the state file, pull requests not seen for 30 days are forgotten.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, v := range s.pulls {
+		if s.now().Sub(v.SeenAt) > expiration {
+			delete(s.pulls, k)
+		}
+	}
+	bs, err := json.MarshalIndent(file{PullRequests: s.pulls}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first to not leave a partially written state
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, bs, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func key(repo string, number int) string {
+	return fmt.Sprintf("%s#%d", repo, number)
+}
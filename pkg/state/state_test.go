@@ -0,0 +1,78 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0644))
+
+	tests := map[string]struct {
+		path    string
+		wantErr bool
+	}{
+		"nonexistent file": {path: filepath.Join(dir, "nonexistent.json")},
+		"invalid file":     {path: filepath.Join(dir, "invalid.json"), wantErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := Load(test.path)
+
+			if !test.wantErr {
+				assert.NotNil(t, s)
+				assert.NoError(t, err)
+			} else {
+				assert.Nil(t, s)
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestStore_Unchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "state.json")
+	s, err := Load(path)
+	require.NoError(t, err)
+
+	assert.False(t, s.Unchanged("owner/name", 1, "sha1", "pull1", "hash1"))
+	s.Record("owner/name", 1, "sha1", "pull1", "hash1")
+	require.NoError(t, s.Save())
+
+	s, err = Load(path)
+	require.NoError(t, err)
+
+	assert.True(t, s.Unchanged("owner/name", 1, "sha1", "pull1", "hash1"))
+	assert.False(t, s.Unchanged("owner/name", 1, "sha2", "pull1", "hash1"))
+	assert.False(t, s.Unchanged("owner/name", 1, "sha1", "pull2", "hash1"))
+	assert.False(t, s.Unchanged("owner/name", 1, "sha1", "pull1", "hash2"))
+	assert.False(t, s.Unchanged("owner/other", 1, "sha1", "pull1", "hash1"))
+	assert.False(t, s.Unchanged("owner/name", 2, "sha1", "pull1", "hash1"))
+	assert.False(t, s.Unchanged("owner/name", 1, "", "pull1", "hash1"))
+}
+
+func TestStore_Save_ForgetsExpiredPullRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Load(path)
+	require.NoError(t, err)
+
+	now := time.Now()
+	s.now = func() time.Time { return now.Add(-expiration - time.Hour) }
+	s.Record("owner/name", 1, "sha1", "pull", "hash")
+	s.now = func() time.Time { return now }
+	s.Record("owner/name", 2, "sha2", "pull", "hash")
+	require.NoError(t, s.Save())
+
+	s, err = Load(path)
+	require.NoError(t, err)
+
+	assert.False(t, s.Unchanged("owner/name", 1, "sha1", "pull", "hash"))
+	assert.True(t, s.Unchanged("owner/name", 2, "sha2", "pull", "hash"))
+}